@@ -0,0 +1,655 @@
+// Package c3 is a grammar agnostic code completion core for ANTLR4 parsers, a port of antlr4-c3.
+// It determines the tokens and (preferred) parser rules which are possible at a caret position by walking the
+// parser's ATN, so it works with any generated parser.
+package c3
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/antlr4-go/antlr/v4"
+)
+
+type pipelineEntry struct {
+	State      antlr.ATNState
+	TokenIndex int
+}
+
+// CandidatesCollection holds the candidates found by CodeCompletionCore.
+type CandidatesCollection struct {
+	// Tokens maps the candidate tokens to the tokens which must follow them, e.g. an opening parenthesis.
+	Tokens map[int][]int
+	// Rules maps the candidate (preferred) rules to the path of rules leading to them, outermost first.
+	Rules map[int][]int
+	// TokenPaths maps the candidate tokens to the rules they were found in, outermost first. If a token is
+	// possible in several places, the first one found is kept.
+	TokenPaths map[int][]int
+	// Incomplete is set if the collection was stopped early, because the context was done or the budget ran out.
+	// The candidates found until then are kept.
+	Incomplete bool
+}
+
+type ruleEndStatus map[int]bool
+
+type followSetWithPath struct {
+	intervals antlr.IntervalSet
+	path      []int
+	following []int
+}
+
+type followSetsList []followSetWithPath
+
+type followSetsHolder struct {
+	sets     followSetsList
+	combined antlr.IntervalSet
+}
+
+type followSetsPerState map[int]followSetsHolder
+
+// FollowSetsCache keeps the follow sets determined by CodeCompletionCore across invocations, per ATN. Follow sets
+// depend on the ignored tokens, so a cache must only be shared between cores with the same IgnoredTokens.
+// It is safe for concurrent use.
+type FollowSetsCache struct {
+	mutex sync.RWMutex
+	sets  map[*antlr.ATN]followSetsPerState
+}
+
+// NewFollowSetsCache returns an empty cache.
+func NewFollowSetsCache() *FollowSetsCache {
+	return &FollowSetsCache{
+		sets: make(map[*antlr.ATN]followSetsPerState),
+	}
+}
+
+// Len returns the number of rule start states with cached follow sets, over all ATNs.
+func (c *FollowSetsCache) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result := 0
+	for _, sets := range c.sets {
+		result += len(sets)
+	}
+	return result
+}
+
+func (c *FollowSetsCache) get(atn *antlr.ATN, state int) (followSetsHolder, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	sets, exists := c.sets[atn][state]
+	return sets, exists
+}
+
+func (c *FollowSetsCache) put(atn *antlr.ATN, state int, sets followSetsHolder) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.sets[atn] == nil {
+		c.sets[atn] = make(followSetsPerState)
+	}
+	c.sets[atn][state] = sets
+}
+
+// CodeCompletionCore collects the candidates at a caret position from the parser's ATN. It holds the state of a
+// single collection and is not safe for concurrent use, create one per request instead. The grammar-derived follow
+// sets, which are expensive to compute, can be shared between cores using a FollowSetsCache.
+type CodeCompletionCore struct {
+	setsPerState followSetsPerState
+	// FollowSetsCache optionally shares the follow sets with other invocations. The cached sets are never modified.
+	FollowSetsCache *FollowSetsCache
+
+	parser antlr.Parser
+	atn    *antlr.ATN
+	// IgnoredTokens are never reported as candidates, e.g. operators or punctuation.
+	IgnoredTokens map[int]bool
+	// PreferredRules are reported as rule candidates instead of the tokens they consist of.
+	PreferredRules map[int]bool
+	// TranslateRulesTopDown selects which preferred rule is reported if the rule stack contains several of them.
+	// By default the outermost one wins, with TranslateRulesTopDown set the innermost one wins.
+	TranslateRulesTopDown bool
+	// MaxStates limits the number of ATN states processed per collection, 0 means no limit.
+	MaxStates int
+	// MaxDuration limits the time spent per collection, 0 means no limit.
+	MaxDuration time.Duration
+	// Trace receives a readable trace of the ATN walk when set, indented by rule depth: rules entered and exited,
+	// states processed, transitions taken, shortcut and follow set hits and the candidates collected.
+	Trace io.Writer
+
+	candidates      *CandidatesCollection
+	shortcutMap     map[int]map[int]ruleEndStatus
+	statesProcessed int
+	ctx             context.Context
+	deadline        time.Time
+	tokenStartIndex int
+	tokens          []int
+}
+
+// NewCodeCompletionCore returns a core collecting candidates from the ATN of the given parser, using the parser's
+// token stream.
+func NewCodeCompletionCore(parser antlr.Parser) *CodeCompletionCore {
+	return &CodeCompletionCore{
+		parser: parser,
+		atn:    parser.GetATN(),
+	}
+}
+
+// CollectCandidates returns the tokens and preferred rules possible at the token with index caretTokenIndex. The
+// collection starts at ruleContext, which must start at or before the caret, or at the first rule of the grammar
+// if ruleContext is nil. Candidate rules are mapped to the path of rules leading to them, candidate tokens to the
+// tokens which must follow them.
+func (c *CodeCompletionCore) CollectCandidates(caretTokenIndex int, ruleContext antlr.ParserRuleContext) *CandidatesCollection {
+	return c.CollectCandidatesContext(context.Background(), caretTokenIndex, ruleContext)
+}
+
+// CollectCandidatesContext works like CollectCandidates, but stops early when ctx is done or the MaxStates or
+// MaxDuration budget is exhausted. The returned collection is marked as incomplete in that case.
+func (c *CodeCompletionCore) CollectCandidatesContext(ctx context.Context, caretTokenIndex int, context antlr.ParserRuleContext) *CandidatesCollection {
+	// init
+	c.candidates = &CandidatesCollection{
+		Tokens:     make(map[int][]int),
+		Rules:      make(map[int][]int),
+		TokenPaths: make(map[int][]int),
+	}
+	c.shortcutMap = make(map[int]map[int]ruleEndStatus)
+	c.statesProcessed = 0
+	c.ctx = ctx
+	c.deadline = time.Time{}
+	if c.MaxDuration > 0 {
+		c.deadline = time.Now().Add(c.MaxDuration)
+	}
+
+	if context == nil {
+		c.tokenStartIndex = 0
+	} else {
+		c.tokenStartIndex = context.GetStart().GetTokenIndex()
+	}
+	c.tokens = []int{}
+
+	// Get all token types, starting with the rule start token, up to the caret token.
+	// These should all have been read already and hence do not place any time penalty on us here.
+	tokenStream := c.parser.GetTokenStream()
+	currentOffset := tokenStream.Index()
+	tokenStream.Seek(c.tokenStartIndex)
+	offset := 1
+	for {
+		token := tokenStream.LT(offset)
+		offset++
+		c.tokens = append(c.tokens, token.GetTokenType())
+
+		if token.GetTokenIndex() >= caretTokenIndex || c.tokens[len(c.tokens)-1] == antlr.TokenEOF {
+			break
+		}
+	}
+	tokenStream.Seek(currentOffset)
+
+	var callStack []int
+	var startRule int
+	if context == nil {
+		startRule = 0
+	} else {
+		startRule = context.GetRuleIndex()
+	}
+	c.processRule(c.atn.GetRuleToStartState(startRule), 0, callStack)
+	return c.candidates
+}
+
+// followSetsForRule returns the follow sets for a rule start state, from the shared cache if possible.
+func (c *CodeCompletionCore) followSetsForRule(startState antlr.ATNState, depth int) followSetsHolder {
+	if c.FollowSetsCache != nil {
+		if sets, exists := c.FollowSetsCache.get(c.atn, startState.GetStateNumber()); exists {
+			if c.Trace != nil {
+				c.trace(depth, "follow sets cache hit")
+			}
+			return sets
+		}
+	}
+
+	stop := c.atn.GetRuleToStopState(startState.GetRuleIndex())
+	sets := followSetsHolder{
+		sets: c.determineFollowSets(startState, stop),
+	}
+	combined := antlr.NewIntervalSet()
+	for _, set := range sets.sets {
+		combined.AddAll(&set.intervals)
+	}
+	sets.combined = *combined
+	if c.Trace != nil {
+		c.trace(depth, "follow sets determined: %d", len(sets.sets))
+	}
+
+	if c.FollowSetsCache != nil {
+		c.FollowSetsCache.put(c.atn, startState.GetStateNumber(), sets)
+	}
+	return sets
+}
+
+func (c *CodeCompletionCore) determineFollowSets(start, stop antlr.ATNState) followSetsList {
+	seen := make(map[antlr.ATNState]bool)
+	ruleStack := []int{}
+	result := followSetsList{}
+	c.collectFollowSets(start, stop, &result, seen, &ruleStack)
+	return result
+}
+
+func (c *CodeCompletionCore) collectFollowSets(s antlr.ATNState, stopState antlr.ATNState, followSets *followSetsList, seen map[antlr.ATNState]bool, ruleStack *[]int) {
+	if _, exists := seen[s]; exists {
+		return
+	}
+
+	seen[s] = true
+
+	if s == stopState || s.GetStateType() == antlr.ATNStateRuleStop {
+		interval := antlr.NewIntervalSet()
+		interval.AddInterval(antlr.NewInterval(antlr.TokenEpsilon, antlr.TokenEpsilon))
+		*followSets = append(*followSets, followSetWithPath{
+			intervals: *interval,
+			path:      append([]int{}, *ruleStack...),
+			following: []int{},
+		})
+		return
+	}
+
+	for _, transition := range s.GetTransitions() {
+		if transition.GetSerializationType() == antlr.TransitionRULE {
+			ruleTransition, ok := transition.(*antlr.RuleTransition)
+			if !ok {
+				panic("should RuleTransition")
+			}
+			if existsInSlice(*ruleStack, ruleTransition.GetTarget().GetRuleIndex()) {
+				continue
+			}
+
+			*ruleStack = append(*ruleStack, ruleTransition.GetTarget().GetRuleIndex())
+			c.collectFollowSets(transition.GetTarget(), stopState, followSets, seen, ruleStack)
+			*ruleStack = (*ruleStack)[:len(*ruleStack)-1]
+		} else if transition.GetSerializationType() == antlr.TransitionPRECEDENCE {
+			predicateTransition, ok := transition.(*antlr.PredicateTransition)
+			if !ok {
+				panic("should PredicateTransition")
+			}
+			if c.checkPredicate(predicateTransition) {
+				c.collectFollowSets(transition.GetTarget(), stopState, followSets, seen, ruleStack)
+			}
+		} else if transition.GetIsEpsilon() {
+			c.collectFollowSets(transition.GetTarget(), stopState, followSets, seen, ruleStack)
+		} else if transition.GetSerializationType() == antlr.TransitionWILDCARD {
+			interval := antlr.NewIntervalSet()
+			interval.AddInterval(antlr.NewInterval(antlr.TokenMinUserTokenType, c.atn.GetMaxTokenType()))
+			*followSets = append(*followSets, followSetWithPath{
+				intervals: *interval,
+				path:      append([]int{}, *ruleStack...),
+				following: []int{},
+			})
+		} else {
+			set := transition.GetLabel()
+			if set != nil && len(set.GetIntervals()) > 0 {
+				if transition.GetSerializationType() == antlr.TransitionNOTSET {
+					set = set.Complement(antlr.TokenMinUserTokenType, c.atn.GetMaxTokenType())
+				}
+				*followSets = append(*followSets, followSetWithPath{
+					intervals: *set,
+					path:      append([]int{}, *ruleStack...),
+					following: c.getFollowingTokens(transition),
+				})
+			}
+		}
+	}
+}
+
+func (c *CodeCompletionCore) getFollowingTokens(transition antlr.Transition) []int {
+	result := []int{}
+	// seen := []antlr.ATNState{}
+	pipeline := []antlr.ATNState{transition.GetTarget()}
+
+	for len(pipeline) > 0 {
+		state := pipeline[len(pipeline)-1]
+		pipeline = pipeline[:len(pipeline)-1]
+
+		for _, transition := range state.GetTransitions() {
+			if transition.GetSerializationType() == antlr.TransitionATOM {
+				if !transition.GetIsEpsilon() {
+					list := transition.GetLabel().ToList()
+					if len(list) == 1 {
+						if _, exists := c.IgnoredTokens[list[0]]; !exists {
+							result = append(result, list[0])
+							pipeline = append(pipeline, transition.GetTarget())
+						}
+					}
+				} else {
+					pipeline = append(pipeline, transition.GetTarget())
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func (c *CodeCompletionCore) checkPredicate(transition *antlr.PredicateTransition) bool {
+	return transition.GetPredicate().Evaluate(c.parser, antlr.ParserRuleContextEmpty)
+}
+
+func existsInSlice(list []int, want int) bool {
+	for _, item := range list {
+		if item == want {
+			return true
+		}
+	}
+	return false
+}
+
+// stopped reports whether the collection has to stop, marking the candidates as incomplete if so.
+func (c *CodeCompletionCore) stopped() bool {
+	if c.candidates.Incomplete {
+		return true
+	}
+	if c.MaxStates > 0 && c.statesProcessed >= c.MaxStates {
+		c.candidates.Incomplete = true
+		return true
+	}
+	// Checking the context and the clock for every state would be too expensive.
+	if c.statesProcessed%64 == 0 {
+		if c.ctx != nil && c.ctx.Err() != nil {
+			c.candidates.Incomplete = true
+		} else if !c.deadline.IsZero() && time.Now().After(c.deadline) {
+			c.candidates.Incomplete = true
+		}
+	}
+	return c.candidates.Incomplete
+}
+
+func (c *CodeCompletionCore) processRule(startState antlr.ATNState, tokenIndex int, callStack []int) ruleEndStatus {
+	if c.stopped() {
+		return ruleEndStatus{}
+	}
+
+	depth := len(callStack)
+	if c.Trace != nil {
+		c.trace(depth, "enter %s (state %d) at token index %d (%s), rule stack: %s", c.ruleName(startState.GetRuleIndex()),
+			startState.GetStateNumber(), tokenIndex, c.tokenName(c.tokens[tokenIndex]), c.ruleStack(append(callStack, startState.GetRuleIndex())))
+	}
+
+	// Start with rule specific handling before going into the ATN traversal.
+
+	// Check first if we've taken this path with the same input before.
+	positionMap := c.shortcutMap[startState.GetRuleIndex()]
+	if positionMap != nil && positionMap[tokenIndex] != nil {
+		if c.Trace != nil {
+			c.trace(depth+1, "shortcut hit, end token indexes: %s", endIndexes(positionMap[tokenIndex]))
+		}
+		return positionMap[tokenIndex]
+	}
+
+	result := make(ruleEndStatus)
+
+	// For rule start states we determine and cache the follow set, which gives us 3 advantages:
+	// 1. We can quickly check if a symbol would be matched when we follow that rule. We can so check in advance
+	//    and can save us all the intermediate steps if there is no match.
+	// 2. We'll have all symbols that are collectable already together when we are at the caret when entering a rule.
+	// 3. We get this lookup for free with any 2nd or further visit of the same rule, which often happens
+	//    in non trivial grammars, especially with (recursive) expressions and of course when invoking code completion
+	//    multiple times.
+	if c.setsPerState == nil {
+		c.setsPerState = make(followSetsPerState)
+	}
+	if _, exists := c.setsPerState[startState.GetStateNumber()]; !exists {
+		c.setsPerState[startState.GetStateNumber()] = c.followSetsForRule(startState, depth+1)
+	} else if c.Trace != nil {
+		c.trace(depth+1, "follow sets hit")
+	}
+
+	followSets := c.setsPerState[startState.GetStateNumber()]
+	callStack = append(callStack, startState.GetRuleIndex())
+
+	if tokenIndex >= len(c.tokens)-1 { // At caret?
+		if c.Trace != nil {
+			c.trace(depth+1, "at caret")
+		}
+		if _, exists := c.PreferredRules[startState.GetRuleIndex()]; exists {
+			// No need to go deeper when collecting entries and we reach a rule that we want to collect anyway.
+			c.translateToRuleIndex(callStack, depth+1)
+		} else {
+			// Convert all follow sets to either single symbols or their associated preferred rule and add
+			// the result to our candidates list.
+			for _, set := range followSets.sets {
+				var fullPath []int
+				fullPath = append(fullPath, callStack...)
+				fullPath = append(fullPath, set.path...)
+				if !c.translateToRuleIndex(fullPath, depth+1) {
+					for _, symbol := range set.intervals.ToList() {
+						if _, exists := c.IgnoredTokens[symbol]; !exists {
+							if _, exists := c.candidates.Tokens[symbol]; !exists {
+								c.candidates.Tokens[symbol] = set.following
+								c.addTokenPath(symbol, fullPath)
+								if c.Trace != nil {
+									c.trace(depth+1, "collected token %s", c.tokenName(symbol))
+								}
+							} else {
+								equal := len(c.candidates.Tokens[symbol]) == len(set.following)
+								if equal {
+									for i, v := range c.candidates.Tokens[symbol] {
+										if v != set.following[i] {
+											equal = false
+											break
+										}
+									}
+								}
+								if !equal {
+									c.candidates.Tokens[symbol] = []int{}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+
+		callStack = callStack[:len(callStack)-1]
+		return ruleEndStatus{}
+	} else {
+		// Process the rule if we either could pass it without consuming anything (epsilon transition)
+		// or if the current input symbol will be matched somewhere after this entry point.
+		currentSymbol := c.tokens[tokenIndex]
+		if !followSets.combined.Contains(antlr.TokenEpsilon) && !followSets.combined.Contains(currentSymbol) {
+			if c.Trace != nil {
+				c.trace(depth+1, "skipped, %s cannot be matched", c.tokenName(currentSymbol))
+			}
+			callStack = callStack[:len(callStack)-1]
+			return ruleEndStatus{}
+		}
+	}
+
+	// The current state execution pipeline contains all yet-to-be-processed ATN states in this rule.
+	// For each such state we store the token index + a list of rules that lead to it
+	var statePipeline []pipelineEntry
+	var currentEntry pipelineEntry
+
+	// Bootstrap the pipeline.
+	statePipeline = append(statePipeline, pipelineEntry{
+		State:      startState,
+		TokenIndex: tokenIndex,
+	})
+
+	for len(statePipeline) != 0 {
+		if c.stopped() {
+			break
+		}
+		currentEntry = statePipeline[len(statePipeline)-1]
+		statePipeline = statePipeline[:len(statePipeline)-1]
+		c.statesProcessed++
+
+		atCaret := currentEntry.TokenIndex >= len(c.tokens)-1
+		if c.Trace != nil {
+			c.trace(depth+1, "state %d (%s) at token index %d", currentEntry.State.GetStateNumber(),
+				stateTypeName(currentEntry.State.GetStateType()), currentEntry.TokenIndex)
+		}
+
+		if currentEntry.State.GetStateType() == antlr.ATNStateRuleStop {
+			result[currentEntry.TokenIndex] = true
+			continue
+		}
+
+		for _, transition := range currentEntry.State.GetTransitions() {
+			if c.Trace != nil {
+				c.trace(depth+2, "%s -> state %d", c.transitionName(transition), transition.GetTarget().GetStateNumber())
+			}
+			switch transition.GetSerializationType() {
+			case antlr.TransitionRULE:
+				endStatus := c.processRule(transition.GetTarget(), currentEntry.TokenIndex, callStack)
+				ruleTransition := transition.(*antlr.RuleTransition)
+				for status := range endStatus {
+					statePipeline = append(statePipeline, pipelineEntry{
+						State:      ruleTransition.GetFollowState(),
+						TokenIndex: status,
+					})
+				}
+			case antlr.TransitionPREDICATE:
+				if c.checkPredicate(transition.(*antlr.PredicateTransition)) {
+					statePipeline = append(statePipeline, pipelineEntry{
+						State:      transition.GetTarget(),
+						TokenIndex: currentEntry.TokenIndex,
+					})
+				}
+			case antlr.TransitionWILDCARD:
+				if atCaret {
+					if !c.translateToRuleIndex(callStack, depth+1) {
+						interval := antlr.NewIntervalSet()
+						interval.AddInterval(antlr.NewInterval(antlr.TokenMinUserTokenType, c.atn.GetMaxTokenType()))
+						for _, token := range interval.ToList() {
+							if _, exists := c.IgnoredTokens[token]; !exists {
+								if _, exists := c.candidates.Tokens[token]; !exists {
+									c.candidates.Tokens[token] = []int{}
+									c.addTokenPath(token, callStack)
+									if c.Trace != nil {
+										c.trace(depth+2, "collected token %s", c.tokenName(token))
+									}
+								}
+							}
+						}
+					}
+				} else {
+					statePipeline = append(statePipeline, pipelineEntry{
+						State:      transition.GetTarget(),
+						TokenIndex: currentEntry.TokenIndex + 1,
+					})
+				}
+			default:
+				if transition.GetIsEpsilon() {
+					if atCaret {
+						c.translateToRuleIndex(callStack, depth+1)
+					}
+
+					// Jump over simple states with a single outgoing epsilon transition.
+					statePipeline = append(statePipeline, pipelineEntry{
+						State:      transition.GetTarget(),
+						TokenIndex: currentEntry.TokenIndex,
+					})
+				}
+
+				set := transition.GetLabel()
+				if set != nil && len(set.GetIntervals()) > 0 {
+					if transition.GetSerializationType() == antlr.TransitionNOTSET {
+						set = set.Complement(antlr.TokenMinUserTokenType, c.atn.GetMaxTokenType())
+					}
+					if atCaret {
+						if !c.translateToRuleIndex(callStack, depth+1) {
+							list := set.ToList()
+							addFollowing := len(list) == 1
+							for _, symbol := range list {
+								if _, exists := c.IgnoredTokens[symbol]; !exists {
+									if addFollowing {
+										c.candidates.Tokens[symbol] = c.getFollowingTokens(transition)
+									} else {
+										c.candidates.Tokens[symbol] = []int{}
+									}
+									c.addTokenPath(symbol, callStack)
+									if c.Trace != nil {
+										c.trace(depth+2, "collected token %s", c.tokenName(symbol))
+									}
+								}
+							}
+						}
+					} else {
+						currentSymbol := c.tokens[currentEntry.TokenIndex]
+						if set.Contains(currentSymbol) {
+							statePipeline = append(statePipeline, pipelineEntry{
+								State:      transition.GetTarget(),
+								TokenIndex: currentEntry.TokenIndex + 1,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if c.Trace != nil {
+		c.trace(depth, "exit %s, end token indexes: %s", c.ruleName(startState.GetRuleIndex()), endIndexes(result))
+	}
+	callStack = callStack[:len(callStack)-1]
+	return result
+}
+
+// addTokenPath records the rule stack in which a candidate token was found, unless one is known already.
+func (c *CodeCompletionCore) addTokenPath(token int, ruleStack []int) {
+	if _, exists := c.candidates.TokenPaths[token]; !exists {
+		c.candidates.TokenPaths[token] = append([]int{}, ruleStack...)
+	}
+}
+
+// translateToRuleIndex adds the preferred rule from the rule stack as candidate, if there is one. The depth is
+// only used for tracing.
+func (c *CodeCompletionCore) translateToRuleIndex(ruleStack []int, depth int) bool {
+	if len(c.PreferredRules) == 0 {
+		return false
+	}
+
+	// Loop over the rule stack from highest to lowest rule level (or the other way around when translating top
+	// down). This way we properly handle the higher rule if it contains a lower one that is also a preferred rule.
+	for n := range ruleStack {
+		i := n
+		if c.TranslateRulesTopDown {
+			i = len(ruleStack) - 1 - n
+		}
+		entry := ruleStack[i]
+		if _, exists := c.PreferredRules[entry]; exists {
+			// Add the rule to our candidates list along with the current rule path,
+			// but only if there isn't already an entry like that.
+			var path []int
+			path = append(path, ruleStack[:i]...)
+			addNew := true
+			for k, v := range c.candidates.Rules {
+				if k != entry || len(v) != len(path) {
+					continue
+				}
+
+				// Found an entry for this rule. Same path? If so don't add a new (duplicate) entry.
+				equal := true
+				for j, p := range path {
+					if p != v[j] {
+						equal = false
+						break
+					}
+				}
+
+				if !equal {
+					addNew = false
+					break
+				}
+			}
+
+			if addNew {
+				if _, exists := c.candidates.Rules[entry]; !exists && c.Trace != nil {
+					c.trace(depth, "collected rule %s, path: %s", c.ruleName(entry), c.ruleStack(path))
+				}
+				c.candidates.Rules[ruleStack[i]] = path
+			}
+			return true
+		}
+	}
+
+	return false
+}
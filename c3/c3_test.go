@@ -0,0 +1,360 @@
+package c3
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/antlr4-go/antlr/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Tokens of the sample grammar.
+const (
+	tokenVar = iota + 1
+	tokenLet
+	tokenID
+	tokenEqual
+	tokenNumber
+	tokenPlus
+)
+
+// Rules of the sample grammar.
+const (
+	ruleStatement = iota
+	ruleAssignment
+	ruleExpr
+	ruleOperand
+	ruleVariableRef
+)
+
+// sampleATN builds the ATN for the grammar
+//
+//	statement:   assignment | expr;
+//	assignment:  (VAR | LET) ID EQUAL expr;
+//	expr:        operand (PLUS operand)?;
+//	operand:     variableRef | NUMBER;
+//	variableRef: ID;
+//
+// the way the ANTLR tool would serialize it.
+func sampleATN() *antlr.ATN {
+	b := &atnBuilder{}
+	starts := make([]int, 5)
+	stops := make([]int, 5)
+	for rule := range starts {
+		starts[rule] = b.state(antlr.ATNStateRuleStart, rule)
+		stops[rule] = b.state(antlr.ATNStateRuleStop, rule)
+	}
+	b.rules = starts
+	call := func(src, rule int) int {
+		follow := b.state(antlr.ATNStateBasic, b.ruleOf(src))
+		b.edge(src, follow, antlr.TransitionRULE, starts[rule], rule, 0)
+		return follow
+	}
+
+	// statement
+	start, end := b.block(ruleStatement)
+	b.epsilon(starts[ruleStatement], start)
+	alternative := b.state(antlr.ATNStateBasic, ruleStatement)
+	b.epsilon(start, alternative)
+	b.epsilon(call(alternative, ruleAssignment), end)
+	alternative = b.state(antlr.ATNStateBasic, ruleStatement)
+	b.epsilon(start, alternative)
+	b.epsilon(call(alternative, ruleExpr), end)
+	b.epsilon(end, stops[ruleStatement])
+
+	// assignment
+	s := b.state(antlr.ATNStateBasic, ruleAssignment)
+	b.epsilon(starts[ruleAssignment], s)
+	s = b.set(s, tokenVar, tokenLet)
+	s = b.atom(s, tokenID)
+	s = b.atom(s, tokenEqual)
+	b.epsilon(call(s, ruleExpr), stops[ruleAssignment])
+
+	// expr
+	s = b.state(antlr.ATNStateBasic, ruleExpr)
+	b.epsilon(starts[ruleExpr], s)
+	start, end = b.block(ruleExpr)
+	b.epsilon(call(s, ruleOperand), start)
+	alternative = b.state(antlr.ATNStateBasic, ruleExpr)
+	b.epsilon(start, alternative)
+	b.epsilon(call(b.atom(alternative, tokenPlus), ruleOperand), end)
+	b.epsilon(start, end)
+	b.epsilon(end, stops[ruleExpr])
+
+	// operand
+	start, end = b.block(ruleOperand)
+	b.epsilon(starts[ruleOperand], start)
+	alternative = b.state(antlr.ATNStateBasic, ruleOperand)
+	b.epsilon(start, alternative)
+	b.epsilon(call(alternative, ruleVariableRef), end)
+	alternative = b.state(antlr.ATNStateBasic, ruleOperand)
+	b.epsilon(start, alternative)
+	b.epsilon(b.atom(alternative, tokenNumber), end)
+	b.epsilon(end, stops[ruleOperand])
+
+	// variableRef
+	s = b.state(antlr.ATNStateBasic, ruleVariableRef)
+	b.epsilon(starts[ruleVariableRef], s)
+	b.epsilon(b.atom(s, tokenID), stops[ruleVariableRef])
+
+	return antlr.NewATNDeserializer(nil).Deserialize(b.serialize(tokenPlus))
+}
+
+// atnBuilder assembles a serialized parser ATN.
+type atnBuilder struct {
+	states    [][]int32
+	rules     []int
+	sets      [][2]int32
+	edges     [][6]int32
+	decisions []int
+}
+
+func (b *atnBuilder) state(stateType, rule int) int {
+	b.states = append(b.states, []int32{int32(stateType), int32(rule)})
+	return len(b.states) - 1
+}
+
+func (b *atnBuilder) ruleOf(state int) int {
+	return int(b.states[state][1])
+}
+
+// block adds a decision block, its alternatives lead from start to end.
+func (b *atnBuilder) block(rule int) (start, end int) {
+	start = b.state(antlr.ATNStateBlockStart, rule)
+	end = b.state(antlr.ATNStateBlockEnd, rule)
+	b.states[start] = append(b.states[start], int32(end))
+	b.decisions = append(b.decisions, start)
+	return start, end
+}
+
+func (b *atnBuilder) edge(src, trg, transitionType, arg1, arg2, arg3 int) {
+	b.edges = append(b.edges, [6]int32{int32(src), int32(trg), int32(transitionType), int32(arg1), int32(arg2), int32(arg3)})
+}
+
+func (b *atnBuilder) epsilon(src, trg int) {
+	b.edge(src, trg, antlr.TransitionEPSILON, 0, 0, 0)
+}
+
+// atom adds a transition matching token from src to a new state, which is returned.
+func (b *atnBuilder) atom(src, token int) int {
+	trg := b.state(antlr.ATNStateBasic, b.ruleOf(src))
+	b.edge(src, trg, antlr.TransitionATOM, token, 0, 0)
+	return trg
+}
+
+// set adds a transition matching the tokens from..to from src to a new state, which is returned.
+func (b *atnBuilder) set(src, from, to int) int {
+	trg := b.state(antlr.ATNStateBasic, b.ruleOf(src))
+	b.sets = append(b.sets, [2]int32{int32(from), int32(to)})
+	b.edge(src, trg, antlr.TransitionSET, len(b.sets)-1, 0, 0)
+	return trg
+}
+
+func (b *atnBuilder) serialize(maxTokenType int) []int32 {
+	data := []int32{4 /* version */, int32(antlr.ATNTypeParser), int32(maxTokenType)}
+	data = append(data, int32(len(b.states)))
+	for _, state := range b.states {
+		data = append(data, state...)
+	}
+	data = append(data, 0 /* non-greedy states */, 0 /* precedence states */)
+	data = append(data, int32(len(b.rules)))
+	for _, start := range b.rules {
+		data = append(data, int32(start))
+	}
+	data = append(data, 0 /* modes */)
+	data = append(data, int32(len(b.sets)))
+	for _, set := range b.sets {
+		data = append(data, 1 /* intervals */, 0 /* contains EOF */, set[0], set[1])
+	}
+	data = append(data, int32(len(b.edges)))
+	for _, edge := range b.edges {
+		data = append(data, edge[:]...)
+	}
+	data = append(data, int32(len(b.decisions)))
+	for _, decision := range b.decisions {
+		data = append(data, int32(decision))
+	}
+	return data
+}
+
+// tokenList is a token stream over a fixed list of tokens, terminated by EOF.
+type tokenList struct {
+	tokens []antlr.Token
+	index  int
+}
+
+func newTokenList(types ...int) *tokenList {
+	result := &tokenList{}
+	for i, tokenType := range append(types, antlr.TokenEOF) {
+		token := antlr.NewCommonToken(&antlr.TokenSourceCharStreamPair{}, tokenType, antlr.TokenDefaultChannel, i, i)
+		token.SetTokenIndex(i)
+		result.tokens = append(result.tokens, token)
+	}
+	return result
+}
+
+func (l *tokenList) Consume() {
+	if l.index < len(l.tokens)-1 {
+		l.index++
+	}
+}
+func (l *tokenList) LA(i int) int                                    { return l.LT(i).GetTokenType() }
+func (l *tokenList) Mark() int                                       { return -1 }
+func (l *tokenList) Release(int)                                     {}
+func (l *tokenList) Index() int                                      { return l.index }
+func (l *tokenList) Seek(index int)                                  { l.index = index }
+func (l *tokenList) Size() int                                       { return len(l.tokens) }
+func (l *tokenList) GetSourceName() string                           { return "tokens" }
+func (l *tokenList) Reset()                                          { l.index = 0 }
+func (l *tokenList) Get(index int) antlr.Token                       { return l.tokens[index] }
+func (l *tokenList) GetTokenSource() antlr.TokenSource               { return nil }
+func (l *tokenList) SetTokenSource(antlr.TokenSource)                {}
+func (l *tokenList) GetAllText() string                              { return "" }
+func (l *tokenList) GetTextFromInterval(antlr.Interval) string       { return "" }
+func (l *tokenList) GetTextFromRuleContext(antlr.RuleContext) string { return "" }
+func (l *tokenList) GetTextFromTokens(_, _ antlr.Token) string       { return "" }
+
+func (l *tokenList) LT(k int) antlr.Token {
+	index := l.index + k - 1
+	if index >= len(l.tokens) {
+		index = len(l.tokens) - 1
+	}
+	return l.tokens[index]
+}
+
+// newSampleParser returns a parser for the sample grammar positioned at the start of the given tokens.
+func newSampleParser(atn *antlr.ATN, tokens ...int) antlr.Parser {
+	parser := antlr.NewBaseParser(nil)
+	parser.RuleNames = []string{"statement", "assignment", "expr", "operand", "variableRef"}
+	parser.SymbolicNames = []string{"", "VAR", "LET", "ID", "EQUAL", "NUMBER", "PLUS"}
+	decisionToDFA := make([]*antlr.DFA, len(atn.DecisionToState))
+	for i, state := range atn.DecisionToState {
+		decisionToDFA[i] = antlr.NewDFA(state, i)
+	}
+	parser.Interpreter = antlr.NewParserATNSimulator(parser, atn, decisionToDFA, antlr.NewPredictionContextCache())
+	parser.SetInputStream(newTokenList(tokens...))
+	return parser
+}
+
+func TestCollectCandidates(t *testing.T) {
+	a := require.New(t)
+	atn := sampleATN()
+
+	// Without preferred rules all tokens possible at the caret are collected, along with the tokens which must
+	// follow them.
+	core := NewCodeCompletionCore(newSampleParser(atn))
+	candidates := core.CollectCandidates(0, nil)
+	a.False(candidates.Incomplete)
+	a.Empty(candidates.Rules)
+	a.Equal(map[int][]int{
+		tokenVar:    {tokenID, tokenEqual},
+		tokenLet:    {tokenID, tokenEqual},
+		tokenID:     {},
+		tokenNumber: {},
+	}, candidates.Tokens)
+	a.Equal(map[int][]int{
+		tokenVar:    {ruleStatement, ruleAssignment},
+		tokenLet:    {ruleStatement, ruleAssignment},
+		tokenID:     {ruleStatement, ruleExpr, ruleOperand, ruleVariableRef},
+		tokenNumber: {ruleStatement, ruleExpr, ruleOperand},
+	}, candidates.TokenPaths)
+
+	core.IgnoredTokens = map[int]bool{tokenLet: true}
+	candidates = core.CollectCandidates(0, nil)
+	a.NotContains(candidates.Tokens, tokenLet)
+	a.Contains(candidates.Tokens, tokenVar)
+
+	// VAR x = |
+	core = NewCodeCompletionCore(newSampleParser(atn, tokenVar, tokenID, tokenEqual))
+	core.PreferredRules = map[int]bool{ruleOperand: true}
+	candidates = core.CollectCandidates(3, nil)
+	a.Equal(map[int][]int{ruleOperand: {ruleStatement, ruleAssignment, ruleExpr}}, candidates.Rules)
+	a.Empty(candidates.Tokens)
+
+	// VAR x = 1 |
+	core = NewCodeCompletionCore(newSampleParser(atn, tokenVar, tokenID, tokenEqual, tokenNumber))
+	core.PreferredRules = map[int]bool{ruleOperand: true}
+	candidates = core.CollectCandidates(4, nil)
+	a.Contains(candidates.Tokens, tokenPlus)
+	a.Equal([]int{ruleStatement, ruleAssignment, ruleExpr}, candidates.TokenPaths[tokenPlus])
+}
+
+func TestTranslateRulesTopDown(t *testing.T) {
+	a := require.New(t)
+	atn := sampleATN()
+
+	// VAR x = |
+	core := NewCodeCompletionCore(newSampleParser(atn, tokenVar, tokenID, tokenEqual))
+	core.PreferredRules = map[int]bool{ruleOperand: true, ruleVariableRef: true}
+
+	// The outermost preferred rule wins by default.
+	candidates := core.CollectCandidates(3, nil)
+	a.Equal(map[int][]int{ruleOperand: {ruleStatement, ruleAssignment, ruleExpr}}, candidates.Rules)
+
+	// Top down the innermost one wins, the NUMBER alternative of operand still yields operand.
+	core.TranslateRulesTopDown = true
+	candidates = core.CollectCandidates(3, nil)
+	a.Equal(map[int][]int{
+		ruleOperand:     {ruleStatement, ruleAssignment, ruleExpr},
+		ruleVariableRef: {ruleStatement, ruleAssignment, ruleExpr, ruleOperand},
+	}, candidates.Rules)
+	a.Empty(candidates.Tokens)
+}
+
+func TestFollowSetsCache(t *testing.T) {
+	a := require.New(t)
+	atn := sampleATN()
+	cache := NewFollowSetsCache()
+
+	collect := func(tokens ...int) *CandidatesCollection {
+		core := NewCodeCompletionCore(newSampleParser(atn, tokens...))
+		core.FollowSetsCache = cache
+		return core.CollectCandidates(len(tokens), nil)
+	}
+
+	first := collect(tokenVar, tokenID, tokenEqual)
+	a.NotZero(cache.Len())
+	a.Equal(first, collect(tokenVar, tokenID, tokenEqual))
+	a.Equal(collect(), NewCodeCompletionCore(newSampleParser(atn)).CollectCandidates(0, nil))
+}
+
+func TestMaxStates(t *testing.T) {
+	a := require.New(t)
+
+	core := NewCodeCompletionCore(newSampleParser(sampleATN(), tokenVar, tokenID, tokenEqual))
+	core.MaxStates = 1
+	a.True(core.CollectCandidates(3, nil).Incomplete)
+
+	core.MaxStates = 0
+	a.False(core.CollectCandidates(3, nil).Incomplete)
+}
+
+func TestTrace(t *testing.T) {
+	a := require.New(t)
+
+	// VAR x = |
+	var trace bytes.Buffer
+	core := NewCodeCompletionCore(newSampleParser(sampleATN(), tokenVar, tokenID, tokenEqual))
+	core.PreferredRules = map[int]bool{ruleOperand: true}
+	core.Trace = &trace
+	candidates := core.CollectCandidates(3, nil)
+	a.Contains(candidates.Rules, ruleOperand)
+
+	lines := strings.Split(trace.String(), "\n")
+	a.Equal("enter statement (state 0) at token index 0 (VAR), rule stack: [statement]", lines[0])
+	a.Contains(lines, "  follow sets determined: 3")
+	a.Contains(lines, "      VAR | LET -> state 17")
+	a.Contains(lines, "  enter assignment (state 2) at token index 0 (VAR), rule stack: [statement > assignment]")
+	a.Contains(lines, "    enter expr (state 4) at token index 3 (EOF), rule stack: [statement > assignment > expr]")
+	a.Contains(lines, "      follow sets hit")
+	a.Contains(lines, "      at caret")
+	a.Contains(lines, "      collected rule operand, path: [statement > assignment > expr]")
+	a.Contains(lines, "  enter expr (state 4) at token index 0 (VAR), rule stack: [statement > expr]")
+	a.Contains(lines, "    skipped, VAR cannot be matched")
+	a.Contains(lines, "  exit assignment, end token indexes: []")
+
+	// The trace doesn't change the result.
+	core.Trace = nil
+	a.Equal(candidates, core.CollectCandidates(3, nil))
+}
@@ -0,0 +1,107 @@
+package c3
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
+)
+
+var stateTypeNames = []string{
+	antlr.ATNStateInvalidType:    "invalid",
+	antlr.ATNStateBasic:          "basic",
+	antlr.ATNStateRuleStart:      "rule start",
+	antlr.ATNStateBlockStart:     "block start",
+	antlr.ATNStatePlusBlockStart: "plus block start",
+	antlr.ATNStateStarBlockStart: "star block start",
+	antlr.ATNStateTokenStart:     "token start",
+	antlr.ATNStateRuleStop:       "rule stop",
+	antlr.ATNStateBlockEnd:       "block end",
+	antlr.ATNStateStarLoopBack:   "star loop back",
+	antlr.ATNStateStarLoopEntry:  "star loop entry",
+	antlr.ATNStatePlusLoopBack:   "plus loop back",
+	antlr.ATNStateLoopEnd:        "loop end",
+}
+
+// trace writes a line to the Trace writer, indented by depth.
+func (c *CodeCompletionCore) trace(depth int, format string, args ...interface{}) {
+	fmt.Fprintf(c.Trace, "%s%s\n", strings.Repeat("  ", depth), fmt.Sprintf(format, args...))
+}
+
+// ruleName returns the name of a rule as known to the parser, or its index.
+func (c *CodeCompletionCore) ruleName(rule int) string {
+	if names := c.parser.GetRuleNames(); rule >= 0 && rule < len(names) && len(names[rule]) != 0 {
+		return names[rule]
+	}
+	return strconv.Itoa(rule)
+}
+
+// tokenName returns the symbolic or literal name of a token type as known to the parser, or the type itself.
+func (c *CodeCompletionCore) tokenName(token int) string {
+	switch token {
+	case antlr.TokenEOF:
+		return "EOF"
+	case antlr.TokenEpsilon:
+		return "ε"
+	}
+	if names := c.parser.GetSymbolicNames(); token >= 0 && token < len(names) && len(names[token]) != 0 {
+		return names[token]
+	}
+	if names := c.parser.GetLiteralNames(); token >= 0 && token < len(names) && len(names[token]) != 0 {
+		return names[token]
+	}
+	return strconv.Itoa(token)
+}
+
+func (c *CodeCompletionCore) ruleStack(rules []int) string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, c.ruleName(rule))
+	}
+	return "[" + strings.Join(names, " > ") + "]"
+}
+
+func (c *CodeCompletionCore) transitionName(transition antlr.Transition) string {
+	switch transition.GetSerializationType() {
+	case antlr.TransitionEPSILON:
+		return "epsilon"
+	case antlr.TransitionRULE:
+		return "rule " + c.ruleName(transition.GetTarget().GetRuleIndex())
+	case antlr.TransitionPREDICATE, antlr.TransitionPRECEDENCE:
+		return "predicate"
+	case antlr.TransitionACTION:
+		return "action"
+	case antlr.TransitionWILDCARD:
+		return "wildcard"
+	}
+
+	var names []string
+	if label := transition.GetLabel(); label != nil {
+		for _, token := range label.ToList() {
+			names = append(names, c.tokenName(token))
+		}
+	}
+	if transition.GetSerializationType() == antlr.TransitionNOTSET {
+		return "not " + strings.Join(names, " | ")
+	}
+	return strings.Join(names, " | ")
+}
+
+func stateTypeName(stateType int) string {
+	if stateType >= 0 && stateType < len(stateTypeNames) {
+		return stateTypeNames[stateType]
+	}
+	return strconv.Itoa(stateType)
+}
+
+// endIndexes returns the sorted token indexes of a rule end status.
+func endIndexes(status ruleEndStatus) string {
+	indexes := make([]int, 0, len(status))
+	for index := range status {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return fmt.Sprint(indexes)
+}
@@ -0,0 +1,39 @@
+package completion
+
+import "unicode/utf8"
+
+// CaretEncoding is the unit in which caret columns and the columns of result positions are counted. Lines are
+// always one-based and columns zero-based, whatever the encoding.
+type CaretEncoding int
+
+const (
+	// CaretEncodingRune counts Unicode code points, like ANTLR does for token columns. This is the default.
+	CaretEncodingRune CaretEncoding = iota
+	// CaretEncodingUTF16 counts UTF-16 code units, as the Language Server Protocol does by default. Characters
+	// outside of the Basic Multilingual Plane, like most emoji, take two units.
+	CaretEncodingUTF16
+	// CaretEncodingByte counts the bytes of the UTF-8 encoded line.
+	CaretEncodingByte
+)
+
+// width returns the number of columns r takes in this encoding.
+func (e CaretEncoding) width(r rune) int {
+	switch e {
+	case CaretEncodingUTF16:
+		if r > 0xFFFF {
+			return 2 // A surrogate pair.
+		}
+	case CaretEncodingByte:
+		if n := utf8.RuneLen(r); n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+// runeColumn converts a column of the given line of text from this encoding into code points. A column within
+// a character is moved to the start of the following character.
+func (e CaretEncoding) runeColumn(text []rune, line, column int) int {
+	index := caretIndex(text, line, column, e)
+	return positionOf(text, index, CaretEncodingRune).Column
+}
@@ -0,0 +1,347 @@
+package completion
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Catalog is a static, in-memory MetadataProvider. It can be built in code or loaded from a YAML or JSON
+// document (e.g. a snapshot of a production schema) and is safe for concurrent use once populated.
+type Catalog struct {
+	Schemas         []*SchemaMetadata `yaml:"schemas,omitempty" json:"schemas,omitempty"`
+	Engines         []string          `yaml:"engines,omitempty" json:"engines,omitempty"`
+	Charsets        []string          `yaml:"charsets,omitempty" json:"charsets,omitempty"`
+	Collations      []string          `yaml:"collations,omitempty" json:"collations,omitempty"`
+	Users           []string          `yaml:"users,omitempty" json:"users,omitempty"`
+	Tablespaces     []string          `yaml:"tablespaces,omitempty" json:"tablespaces,omitempty"`
+	LogfileGroups   []string          `yaml:"logfileGroups,omitempty" json:"logfileGroups,omitempty"`
+	SystemVariables []string          `yaml:"systemVariables,omitempty" json:"systemVariables,omitempty"`
+}
+
+type SchemaMetadata struct {
+	Name     string             `yaml:"name" json:"name"`
+	Tables   []*TableMetadata   `yaml:"tables,omitempty" json:"tables,omitempty"`
+	Views    []*ViewMetadata    `yaml:"views,omitempty" json:"views,omitempty"`
+	Routines []*RoutineMetadata `yaml:"routines,omitempty" json:"routines,omitempty"`
+	Triggers []*TriggerMetadata `yaml:"triggers,omitempty" json:"triggers,omitempty"`
+	Events   []*EventMetadata   `yaml:"events,omitempty" json:"events,omitempty"`
+}
+
+type TableMetadata struct {
+	Name    string            `yaml:"name" json:"name"`
+	Engine  string            `yaml:"engine,omitempty" json:"engine,omitempty"`
+	Comment string            `yaml:"comment,omitempty" json:"comment,omitempty"`
+	Columns []*ColumnMetadata `yaml:"columns,omitempty" json:"columns,omitempty"`
+	Indexes []*IndexMetadata  `yaml:"indexes,omitempty" json:"indexes,omitempty"`
+}
+
+type ViewMetadata struct {
+	Name       string            `yaml:"name" json:"name"`
+	Definition string            `yaml:"definition,omitempty" json:"definition,omitempty"`
+	Comment    string            `yaml:"comment,omitempty" json:"comment,omitempty"`
+	Columns    []*ColumnMetadata `yaml:"columns,omitempty" json:"columns,omitempty"`
+}
+
+type ColumnMetadata struct {
+	Name     string  `yaml:"name" json:"name"`
+	Type     string  `yaml:"type,omitempty" json:"type,omitempty"`
+	Nullable bool    `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+	Default  *string `yaml:"default,omitempty" json:"default,omitempty"`
+	Comment  string  `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+type IndexMetadata struct {
+	Name    string   `yaml:"name" json:"name"`
+	Columns []string `yaml:"columns,omitempty" json:"columns,omitempty"`
+	Unique  bool     `yaml:"unique,omitempty" json:"unique,omitempty"`
+}
+
+type RoutineType string
+
+const (
+	RoutineTypeFunction  RoutineType = "FUNCTION"
+	RoutineTypeProcedure RoutineType = "PROCEDURE"
+)
+
+type RoutineMetadata struct {
+	Name       string               `yaml:"name" json:"name"`
+	Type       RoutineType          `yaml:"type" json:"type"`
+	Parameters []*ParameterMetadata `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// Returns is the return type of a stored function, empty for procedures.
+	Returns string `yaml:"returns,omitempty" json:"returns,omitempty"`
+	Comment string `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+type ParameterMetadata struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Mode is one of IN, OUT or INOUT. It is only used for procedure parameters.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+}
+
+type TriggerMetadata struct {
+	Name   string `yaml:"name" json:"name"`
+	Table  string `yaml:"table,omitempty" json:"table,omitempty"`
+	Timing string `yaml:"timing,omitempty" json:"timing,omitempty"`
+	Event  string `yaml:"event,omitempty" json:"event,omitempty"`
+}
+
+type EventMetadata struct {
+	Name    string `yaml:"name" json:"name"`
+	Comment string `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// NewCatalogFromYAML parses a catalog from its YAML representation.
+func NewCatalogFromYAML(data []byte) (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := yaml.Unmarshal(data, catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML catalog: %w", err)
+	}
+	return catalog, nil
+}
+
+// NewCatalogFromJSON parses a catalog from its JSON representation.
+func NewCatalogFromJSON(data []byte) (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := json.Unmarshal(data, catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON catalog: %w", err)
+	}
+	return catalog, nil
+}
+
+// LoadCatalog reads a catalog file. Files with a .json extension are parsed as JSON, everything else as YAML.
+func LoadCatalog(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog %q: %w", path, err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return NewCatalogFromJSON(data)
+	}
+	return NewCatalogFromYAML(data)
+}
+
+// Schema returns the schema with the given name or nil if there is none. Object names are compared
+// case-insensitively throughout the catalog.
+func (c *Catalog) Schema(name string) *SchemaMetadata {
+	for _, schema := range c.Schemas {
+		if strings.EqualFold(schema.Name, name) {
+			return schema
+		}
+	}
+	return nil
+}
+
+func (s *SchemaMetadata) Table(name string) *TableMetadata {
+	for _, table := range s.Tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+func (s *SchemaMetadata) View(name string) *ViewMetadata {
+	for _, view := range s.Views {
+		if strings.EqualFold(view.Name, name) {
+			return view
+		}
+	}
+	return nil
+}
+
+func (s *SchemaMetadata) Routine(name string, routineType RoutineType) *RoutineMetadata {
+	for _, routine := range s.Routines {
+		if routine.Type == routineType && strings.EqualFold(routine.Name, name) {
+			return routine
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) ListSchemas() []string {
+	var result []string
+	for _, schema := range c.Schemas {
+		result = append(result, schema.Name)
+	}
+	return result
+}
+
+func (c *Catalog) ListTables(schema string) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var result []string
+	for _, table := range s.Tables {
+		result = append(result, table.Name)
+	}
+	return result
+}
+
+func (c *Catalog) ListViews(schema string) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var result []string
+	for _, view := range s.Views {
+		result = append(result, view.Name)
+	}
+	return result
+}
+
+// ListColumns returns the columns of a table or, if there is no such table, of a view.
+func (c *Catalog) ListColumns(schema, table string) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var columns []*ColumnMetadata
+	if t := s.Table(table); t != nil {
+		columns = t.Columns
+	} else if v := s.View(table); v != nil {
+		columns = v.Columns
+	}
+	var result []string
+	for _, column := range columns {
+		result = append(result, column.Name)
+	}
+	return result
+}
+
+func (c *Catalog) ListIndexes(schema, table string) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	t := s.Table(table)
+	if t == nil {
+		return nil
+	}
+	var result []string
+	for _, index := range t.Indexes {
+		result = append(result, index.Name)
+	}
+	return result
+}
+
+func (c *Catalog) ListFunctions(schema string) []string {
+	return c.listRoutines(schema, RoutineTypeFunction)
+}
+
+func (c *Catalog) ListProcedures(schema string) []string {
+	return c.listRoutines(schema, RoutineTypeProcedure)
+}
+
+func (c *Catalog) listRoutines(schema string, routineType RoutineType) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var result []string
+	for _, routine := range s.Routines {
+		if routine.Type == routineType {
+			result = append(result, routine.Name)
+		}
+	}
+	return result
+}
+
+func (c *Catalog) ListTriggers(schema string) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var result []string
+	for _, trigger := range s.Triggers {
+		result = append(result, trigger.Name)
+	}
+	return result
+}
+
+func (c *Catalog) ListEvents(schema string) []string {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var result []string
+	for _, event := range s.Events {
+		result = append(result, event.Name)
+	}
+	return result
+}
+
+func (c *Catalog) ListEngines() []string {
+	return c.Engines
+}
+
+func (c *Catalog) ListCharsets() []string {
+	return c.Charsets
+}
+
+func (c *Catalog) ListCollations() []string {
+	return c.Collations
+}
+
+func (c *Catalog) ListUsers() []string {
+	return c.Users
+}
+
+func (c *Catalog) ListTablespaces() []string {
+	return c.Tablespaces
+}
+
+func (c *Catalog) ListLogfileGroups() []string {
+	return c.LogfileGroups
+}
+
+func (c *Catalog) ListSystemVariables() []string {
+	return c.SystemVariables
+}
+
+func (c *Catalog) DescribeTable(schema, table string) *TableMetadata {
+	if s := c.Schema(schema); s != nil {
+		return s.Table(table)
+	}
+	return nil
+}
+
+func (c *Catalog) DescribeView(schema, view string) *ViewMetadata {
+	if s := c.Schema(schema); s != nil {
+		return s.View(view)
+	}
+	return nil
+}
+
+// DescribeColumn looks up a column of a table or, if there is no such table, of a view.
+func (c *Catalog) DescribeColumn(schema, table, column string) *ColumnMetadata {
+	s := c.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	var columns []*ColumnMetadata
+	if t := s.Table(table); t != nil {
+		columns = t.Columns
+	} else if v := s.View(table); v != nil {
+		columns = v.Columns
+	}
+	for _, candidate := range columns {
+		if strings.EqualFold(candidate.Name, column) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) DescribeRoutine(schema, name string, routineType RoutineType) *RoutineMetadata {
+	if s := c.Schema(schema); s != nil {
+		return s.Routine(name, routineType)
+	}
+	return nil
+}
@@ -0,0 +1,637 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// NewCatalogFromDDL builds a catalog from a DDL script, e.g. the output of `mysqldump --no-data`.
+// CREATE DATABASE, CREATE TABLE, CREATE INDEX, CREATE VIEW, CREATE PROCEDURE/FUNCTION, CREATE TRIGGER,
+// CREATE EVENT and ALTER TABLE statements are applied in order, USE switches the schema for unqualified names.
+// Objects which are neither qualified nor preceded by a USE statement are placed in defaultSchema.
+// Other statements are ignored. The script is split into statements like the mysql command line client does it
+// (see SplitStatements), so DELIMITER commands around triggers and routines are supported.
+// Statements with syntax errors are skipped. The returned error lists them, the catalog is returned anyway and
+// holds the objects of all other statements.
+func NewCatalogFromDDL(script string, defaultSchema string) (*Catalog, error) {
+	builder := &catalogBuilder{
+		catalog:       &Catalog{},
+		currentSchema: defaultSchema,
+	}
+
+	var errors []string
+	for _, statement := range SplitStatements(script) {
+		tree, statementErrors := parseDDLStatement(statement)
+		if len(statementErrors) > 0 {
+			errors = append(errors, statementErrors...)
+			continue
+		}
+		antlr.ParseTreeWalkerDefault.Walk(builder, tree)
+	}
+
+	if len(errors) > 0 {
+		return builder.catalog, fmt.Errorf("failed to parse DDL script: %s", strings.Join(errors, "; "))
+	}
+	return builder.catalog, nil
+}
+
+// parseDDLStatement parses a single statement of a DDL script. Error positions refer to the script.
+func parseDDLStatement(statement Statement) (mysql.IScriptContext, []string) {
+	// The grammar requires a terminating semicolon, on its own line in case the statement ends with a comment.
+	input := antlr.NewInputStream(statement.Text + "\n;")
+	lexer := mysql.NewMySQLLexer(input)
+	tokens := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
+	parser := mysql.NewMySQLParser(tokens)
+
+	errors := &syntaxErrorListener{start: statement.Start}
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(errors)
+	parser.RemoveErrorListeners()
+	parser.AddErrorListener(errors)
+
+	parser.BuildParseTrees = true
+	tree := parser.Script()
+	return tree, errors.errors
+}
+
+type syntaxErrorListener struct {
+	*antlr.DefaultErrorListener
+
+	// The position of the statement in the script.
+	start  Position
+	errors []string
+}
+
+func (l *syntaxErrorListener) SyntaxError(_ antlr.Recognizer, _ interface{}, line, column int, msg string, _ antlr.RecognitionException) {
+	position := l.start.add(Position{Line: line, Column: column})
+	l.errors = append(l.errors, fmt.Sprintf("line %d:%d %s", position.Line, position.Column, msg))
+}
+
+// catalogBuilder applies the DDL statements of a parsed script to a catalog.
+type catalogBuilder struct {
+	*mysql.BaseMySQLParserListener
+
+	catalog       *Catalog
+	currentSchema string
+	// The nesting depth of routine, trigger and event bodies. Objects created or altered in a body only exist
+	// when it is executed, so they are not added to the catalog.
+	bodyDepth int
+}
+
+func (b *catalogBuilder) EnterUseCommand(ctx *mysql.UseCommandContext) {
+	b.currentSchema = unquote(ctx.Identifier().GetText())
+}
+
+func (b *catalogBuilder) EnterCreateDatabase(ctx *mysql.CreateDatabaseContext) {
+	b.schema(unquote(ctx.SchemaName().GetText()))
+}
+
+func (b *catalogBuilder) EnterCreateTable(ctx *mysql.CreateTableContext) {
+	if b.bodyDepth > 0 {
+		return
+	}
+	schemaName, tableName := splitObjectName(ctx.TableName())
+	if _, existing := b.findTable(schemaName, tableName); existing != nil && ctx.IfNotExists() != nil {
+		return
+	}
+	schema := b.schema(schemaName)
+	table := &TableMetadata{Name: tableName}
+
+	if ctx.TableRef() != nil {
+		// CREATE TABLE ... LIKE: copy the definition of the source table, if we know it.
+		if _, source := b.findTable(splitObjectName(ctx.TableRef())); source != nil {
+			for _, column := range source.Columns {
+				copied := *column
+				table.Columns = append(table.Columns, &copied)
+			}
+			for _, index := range source.Indexes {
+				copied := *index
+				table.Indexes = append(table.Indexes, &copied)
+			}
+			table.Engine = source.Engine
+			table.Comment = source.Comment
+		}
+	}
+
+	if ctx.TableElementList() != nil {
+		for _, element := range ctx.TableElementList().AllTableElement() {
+			b.addTableElement(table, element)
+		}
+	}
+
+	if ctx.CreateTableOptions() != nil {
+		for _, option := range ctx.CreateTableOptions().AllCreateTableOption() {
+			b.applyTableOption(table, option)
+		}
+	}
+
+	schema.Tables = removeTable(schema.Tables, tableName)
+	schema.Tables = append(schema.Tables, table)
+}
+
+func (b *catalogBuilder) EnterCreateIndex(ctx *mysql.CreateIndexContext) {
+	if b.bodyDepth > 0 {
+		return
+	}
+	target := ctx.CreateIndexTarget()
+	_, table := b.findTable(splitObjectName(target.TableRef()))
+	if table == nil {
+		return
+	}
+
+	var name string
+	if ctx.IndexName() != nil {
+		name = unquote(ctx.IndexName().GetText())
+	} else if ctx.IndexNameAndType() != nil {
+		name = unquote(ctx.IndexNameAndType().IndexName().GetText())
+	}
+	table.Indexes = append(table.Indexes, &IndexMetadata{
+		Name:    name,
+		Columns: b.keyParts(target.KeyListVariants()),
+		Unique:  ctx.UNIQUE_SYMBOL() != nil,
+	})
+}
+
+func (b *catalogBuilder) EnterCreateView(ctx *mysql.CreateViewContext) {
+	schemaName, viewName := splitObjectName(ctx.ViewName())
+	schema := b.schema(schemaName)
+	view := &ViewMetadata{
+		Name:       viewName,
+		Definition: b.text(ctx.ViewTail().ViewSelect()),
+	}
+
+	if list := ctx.ViewTail().ColumnInternalRefList(); list != nil {
+		for _, column := range list.AllColumnInternalRef() {
+			view.Columns = append(view.Columns, &ColumnMetadata{Name: unquote(column.GetText())})
+		}
+	} else if items := firstSelectItemList(ctx.ViewTail().ViewSelect()); items != nil {
+		for _, item := range items.AllSelectItem() {
+			if item.Expr() == nil {
+				continue // Wildcards can only be resolved with the source tables at hand.
+			}
+			view.Columns = append(view.Columns, &ColumnMetadata{Name: b.selectItemName(item)})
+		}
+	}
+
+	for i, existing := range schema.Views {
+		if strings.EqualFold(existing.Name, viewName) {
+			schema.Views = append(schema.Views[:i], schema.Views[i+1:]...)
+			break
+		}
+	}
+	schema.Views = append(schema.Views, view)
+}
+
+func (b *catalogBuilder) EnterCreateProcedure(ctx *mysql.CreateProcedureContext) {
+	schemaName, name := splitQualifiedIdentifier(ctx.ProcedureName().QualifiedIdentifier())
+	routine := &RoutineMetadata{
+		Name: name,
+		Type: RoutineTypeProcedure,
+	}
+	for _, parameter := range ctx.AllProcedureParameter() {
+		mode := "IN"
+		if parameter.GetType_() != nil {
+			mode = strings.ToUpper(parameter.GetType_().GetText())
+		}
+		routine.Parameters = append(routine.Parameters, &ParameterMetadata{
+			Name: unquote(parameter.FunctionParameter().ParameterName().GetText()),
+			Type: b.text(parameter.FunctionParameter().TypeWithOptCollate()),
+			Mode: mode,
+		})
+	}
+	b.addRoutine(schemaName, routine)
+	b.bodyDepth++
+}
+
+func (b *catalogBuilder) ExitCreateProcedure(_ *mysql.CreateProcedureContext) {
+	b.bodyDepth--
+}
+
+func (b *catalogBuilder) EnterCreateFunction(ctx *mysql.CreateFunctionContext) {
+	schemaName, name := splitQualifiedIdentifier(ctx.FunctionName().QualifiedIdentifier())
+	routine := &RoutineMetadata{
+		Name:    name,
+		Type:    RoutineTypeFunction,
+		Returns: b.text(ctx.TypeWithOptCollate()),
+	}
+	for _, parameter := range ctx.AllFunctionParameter() {
+		routine.Parameters = append(routine.Parameters, &ParameterMetadata{
+			Name: unquote(parameter.ParameterName().GetText()),
+			Type: b.text(parameter.TypeWithOptCollate()),
+		})
+	}
+	b.addRoutine(schemaName, routine)
+	b.bodyDepth++
+}
+
+func (b *catalogBuilder) ExitCreateFunction(_ *mysql.CreateFunctionContext) {
+	b.bodyDepth--
+}
+
+func (b *catalogBuilder) EnterCreateTrigger(ctx *mysql.CreateTriggerContext) {
+	schemaName, name := splitQualifiedIdentifier(ctx.TriggerName().QualifiedIdentifier())
+	_, tableName := splitObjectName(ctx.TableRef())
+	schema := b.schema(schemaName)
+	schema.Triggers = append(schema.Triggers, &TriggerMetadata{
+		Name:   name,
+		Table:  tableName,
+		Timing: strings.ToUpper(ctx.GetTiming().GetText()),
+		Event:  strings.ToUpper(ctx.GetEvent().GetText()),
+	})
+	b.bodyDepth++
+}
+
+func (b *catalogBuilder) ExitCreateTrigger(_ *mysql.CreateTriggerContext) {
+	b.bodyDepth--
+}
+
+func (b *catalogBuilder) EnterCreateEvent(ctx *mysql.CreateEventContext) {
+	schemaName, name := splitQualifiedIdentifier(ctx.EventName().QualifiedIdentifier())
+	event := &EventMetadata{Name: name}
+	if ctx.TextLiteral() != nil {
+		event.Comment = unquote(ctx.TextLiteral().GetText())
+	}
+	schema := b.schema(schemaName)
+	schema.Events = append(schema.Events, event)
+	b.bodyDepth++
+}
+
+func (b *catalogBuilder) ExitCreateEvent(_ *mysql.CreateEventContext) {
+	b.bodyDepth--
+}
+
+func (b *catalogBuilder) EnterAlterTable(ctx *mysql.AlterTableContext) {
+	if b.bodyDepth > 0 {
+		return
+	}
+	schema, table := b.findTable(splitObjectName(ctx.TableRef()))
+	if table == nil || ctx.AlterTableActions() == nil || ctx.AlterTableActions().AlterCommandList() == nil {
+		return
+	}
+
+	alterList := ctx.AlterTableActions().AlterCommandList().AlterList()
+	if alterList == nil {
+		return
+	}
+
+	for _, options := range alterList.AllCreateTableOptionsSpaceSeparated() {
+		for _, option := range options.AllCreateTableOption() {
+			b.applyTableOption(table, option)
+		}
+	}
+
+	for _, item := range alterList.AllAlterListItem() {
+		switch {
+		case item.ADD_SYMBOL() != nil && item.TableConstraintDef() != nil:
+			b.addTableElementConstraint(table, item.TableConstraintDef())
+		case item.ADD_SYMBOL() != nil && item.TableElementList() != nil:
+			for _, element := range item.TableElementList().AllTableElement() {
+				b.addTableElement(table, element)
+			}
+		case item.ADD_SYMBOL() != nil:
+			table.Columns = append(table.Columns, b.column(unquote(item.Identifier().GetText()), item.FieldDefinition(), table))
+		case item.CHANGE_SYMBOL() != nil:
+			column := b.column(unquote(item.Identifier().GetText()), item.FieldDefinition(), table)
+			replaceColumn(table, unquote(item.ColumnInternalRef().GetText()), column)
+		case item.MODIFY_SYMBOL() != nil:
+			name := unquote(item.ColumnInternalRef().GetText())
+			replaceColumn(table, name, b.column(name, item.FieldDefinition(), table))
+		case item.RENAME_SYMBOL() != nil && item.COLUMN_SYMBOL() != nil:
+			if column := findColumn(table.Columns, unquote(item.ColumnInternalRef().GetText())); column != nil {
+				column.Name = unquote(item.Identifier().GetText())
+			}
+		case item.RENAME_SYMBOL() != nil && item.TableName() != nil:
+			newSchemaName, newTableName := splitObjectName(item.TableName())
+			schema.Tables = removeTable(schema.Tables, table.Name)
+			table.Name = newTableName
+			target := b.schema(newSchemaName)
+			target.Tables = append(target.Tables, table)
+		case item.RENAME_SYMBOL() != nil && item.IndexRef() != nil:
+			name := unquote(item.IndexRef().GetText())
+			for _, index := range table.Indexes {
+				if strings.EqualFold(index.Name, name) {
+					index.Name = unquote(item.IndexName().GetText())
+				}
+			}
+		case item.DROP_SYMBOL() != nil && item.PRIMARY_SYMBOL() != nil:
+			table.Indexes = removeIndex(table.Indexes, "PRIMARY")
+		case item.DROP_SYMBOL() != nil && item.IndexRef() != nil:
+			table.Indexes = removeIndex(table.Indexes, unquote(item.IndexRef().GetText()))
+		case item.DROP_SYMBOL() != nil && item.ColumnInternalRef() != nil && item.FOREIGN_SYMBOL() == nil:
+			name := unquote(item.ColumnInternalRef().GetText())
+			for i, column := range table.Columns {
+				if strings.EqualFold(column.Name, name) {
+					table.Columns = append(table.Columns[:i], table.Columns[i+1:]...)
+					break
+				}
+			}
+		case item.ALTER_SYMBOL() != nil && item.ColumnInternalRef() != nil:
+			column := findColumn(table.Columns, unquote(item.ColumnInternalRef().GetText()))
+			if column == nil {
+				break
+			}
+			if item.DROP_SYMBOL() != nil {
+				column.Default = nil
+			} else if item.SignedLiteral() != nil {
+				value := unquote(item.SignedLiteral().GetText())
+				column.Default = &value
+			} else if item.ExprWithParentheses() != nil {
+				value := b.text(item.ExprWithParentheses())
+				column.Default = &value
+			}
+		}
+	}
+}
+
+// schema returns the schema with the given name, creating it if necessary. An empty name stands for the
+// current schema.
+func (b *catalogBuilder) schema(name string) *SchemaMetadata {
+	if len(name) == 0 {
+		name = b.currentSchema
+	}
+	if schema := b.catalog.Schema(name); schema != nil {
+		return schema
+	}
+	schema := &SchemaMetadata{Name: name}
+	b.catalog.Schemas = append(b.catalog.Schemas, schema)
+	return schema
+}
+
+// findTable looks up an existing table. An empty schema name stands for the current schema.
+func (b *catalogBuilder) findTable(schemaName, tableName string) (*SchemaMetadata, *TableMetadata) {
+	if len(schemaName) == 0 {
+		schemaName = b.currentSchema
+	}
+	schema := b.catalog.Schema(schemaName)
+	if schema == nil {
+		return nil, nil
+	}
+	return schema, schema.Table(tableName)
+}
+
+func (b *catalogBuilder) addRoutine(schemaName string, routine *RoutineMetadata) {
+	schema := b.schema(schemaName)
+	for i, existing := range schema.Routines {
+		if existing.Type == routine.Type && strings.EqualFold(existing.Name, routine.Name) {
+			schema.Routines = append(schema.Routines[:i], schema.Routines[i+1:]...)
+			break
+		}
+	}
+	schema.Routines = append(schema.Routines, routine)
+}
+
+func (b *catalogBuilder) addTableElement(table *TableMetadata, element mysql.ITableElementContext) {
+	if definition := element.ColumnDefinition(); definition != nil {
+		name := definition.ColumnName().GetText()
+		if definition.ColumnName().FieldIdentifier() != nil {
+			// A qualified column name, use only the last part.
+			name = name[strings.LastIndex(name, ".")+1:]
+		}
+		table.Columns = append(table.Columns, b.column(unquote(name), definition.FieldDefinition(), table))
+		return
+	}
+
+	b.addTableElementConstraint(table, element.TableConstraintDef())
+}
+
+func (b *catalogBuilder) addTableElementConstraint(table *TableMetadata, constraint mysql.ITableConstraintDefContext) {
+	if constraint == nil || constraint.GetType_() == nil {
+		return // CHECK constraints.
+	}
+
+	index := &IndexMetadata{}
+	switch constraint.GetType_().GetTokenType() {
+	case mysql.MySQLLexerFOREIGN_SYMBOL:
+		return
+	case mysql.MySQLLexerPRIMARY_SYMBOL:
+		index.Name = "PRIMARY"
+		index.Unique = true
+	case mysql.MySQLLexerUNIQUE_SYMBOL:
+		index.Unique = true
+	}
+
+	if len(index.Name) == 0 {
+		if constraint.IndexNameAndType() != nil {
+			index.Name = unquote(constraint.IndexNameAndType().IndexName().GetText())
+		} else if constraint.IndexName() != nil {
+			index.Name = unquote(constraint.IndexName().GetText())
+		} else if constraint.ConstraintName() != nil && constraint.ConstraintName().Identifier() != nil {
+			index.Name = unquote(constraint.ConstraintName().Identifier().GetText())
+		}
+	}
+	index.Columns = b.keyParts(constraint.KeyListVariants())
+	if len(index.Name) == 0 && len(index.Columns) > 0 {
+		// The server names unnamed indexes after their first column.
+		index.Name = index.Columns[0]
+	}
+
+	if index.Name == "PRIMARY" {
+		for _, name := range index.Columns {
+			if column := findColumn(table.Columns, name); column != nil {
+				column.Nullable = false
+			}
+		}
+	}
+	table.Indexes = append(table.Indexes, index)
+}
+
+func (b *catalogBuilder) keyParts(variants mysql.IKeyListVariantsContext) []string {
+	if variants == nil {
+		return nil
+	}
+
+	var result []string
+	if variants.KeyList() != nil {
+		for _, part := range variants.KeyList().AllKeyPart() {
+			result = append(result, unquote(part.Identifier().GetText()))
+		}
+	} else if variants.KeyListWithExpression() != nil {
+		for _, part := range variants.KeyListWithExpression().AllKeyPartOrExpression() {
+			if part.KeyPart() != nil {
+				result = append(result, unquote(part.KeyPart().Identifier().GetText()))
+			} else {
+				result = append(result, b.text(part))
+			}
+		}
+	}
+	return result
+}
+
+// column creates the column metadata for a column definition. Inline PRIMARY KEY and UNIQUE attributes
+// also add an index to the table.
+func (b *catalogBuilder) column(name string, definition mysql.IFieldDefinitionContext, table *TableMetadata) *ColumnMetadata {
+	column := &ColumnMetadata{
+		Name:     name,
+		Type:     b.text(definition.DataType()),
+		Nullable: true,
+	}
+
+	for _, attribute := range definition.AllColumnAttribute() {
+		switch {
+		case attribute.NullLiteral() != nil:
+			column.Nullable = attribute.NOT_SYMBOL() == nil
+		case attribute.GetValue() == nil:
+			continue
+		case attribute.GetValue().GetTokenType() == mysql.MySQLLexerDEFAULT_SYMBOL:
+			var value string
+			switch {
+			case attribute.SignedLiteral() != nil:
+				value = unquote(attribute.SignedLiteral().GetText())
+			case attribute.NOW_SYMBOL() != nil:
+				value = strings.ToUpper(attribute.NOW_SYMBOL().GetText())
+				if attribute.TimeFunctionParameters() != nil {
+					value += attribute.TimeFunctionParameters().GetText()
+				}
+			case attribute.ExprWithParentheses() != nil:
+				value = b.text(attribute.ExprWithParentheses())
+			}
+			column.Default = &value
+		case attribute.GetValue().GetTokenType() == mysql.MySQLLexerCOMMENT_SYMBOL:
+			column.Comment = unquote(attribute.TextLiteral().GetText())
+		case attribute.GetValue().GetTokenType() == mysql.MySQLLexerKEY_SYMBOL && attribute.PRIMARY_SYMBOL() != nil:
+			column.Nullable = false
+			table.Indexes = append(table.Indexes, &IndexMetadata{
+				Name:    "PRIMARY",
+				Columns: []string{name},
+				Unique:  true,
+			})
+		case attribute.GetValue().GetTokenType() == mysql.MySQLLexerUNIQUE_SYMBOL:
+			table.Indexes = append(table.Indexes, &IndexMetadata{
+				Name:    name,
+				Columns: []string{name},
+				Unique:  true,
+			})
+		}
+	}
+	return column
+}
+
+func (b *catalogBuilder) applyTableOption(table *TableMetadata, option mysql.ICreateTableOptionContext) {
+	if option.GetOption() == nil {
+		return
+	}
+
+	switch option.GetOption().GetTokenType() {
+	case mysql.MySQLLexerENGINE_SYMBOL:
+		table.Engine = unquote(option.EngineRef().GetText())
+	case mysql.MySQLLexerCOMMENT_SYMBOL:
+		table.Comment = unquote(option.TextStringLiteral().GetText())
+	}
+}
+
+func (b *catalogBuilder) selectItemName(item mysql.ISelectItemContext) string {
+	if alias := item.SelectAlias(); alias != nil {
+		if alias.Identifier() != nil {
+			return unquote(alias.Identifier().GetText())
+		}
+		return unquote(alias.TextStringLiteral().GetText())
+	}
+
+	// A plain column reference is named after the column, everything else after the expression text.
+	text := item.Expr().GetText()
+	if isColumnPath(text) {
+		return unquote(text[strings.LastIndex(text, ".")+1:])
+	}
+	return b.text(item.Expr())
+}
+
+// text returns the original input text of the given context, including whitespace.
+func (b *catalogBuilder) text(ctx antlr.ParserRuleContext) string {
+	start, stop := ctx.GetStart(), ctx.GetStop()
+	if start == nil || stop == nil || stop.GetStop() < start.GetStart() {
+		return ""
+	}
+	return start.GetInputStream().GetText(start.GetStart(), stop.GetStop())
+}
+
+// objectName is implemented by the name and reference contexts, which allow a dot identifier alternative
+// for the qualified identifier (e.g. tableName, tableRef, viewName).
+type objectName interface {
+	QualifiedIdentifier() mysql.IQualifiedIdentifierContext
+	DotIdentifier() mysql.IDotIdentifierContext
+}
+
+func splitObjectName(ctx objectName) (schema, name string) {
+	if ctx.QualifiedIdentifier() != nil {
+		return splitQualifiedIdentifier(ctx.QualifiedIdentifier())
+	}
+	return "", unquote(ctx.DotIdentifier().Identifier().GetText())
+}
+
+func splitQualifiedIdentifier(ctx mysql.IQualifiedIdentifierContext) (schema, name string) {
+	name = unquote(ctx.Identifier().GetText())
+	if ctx.DotIdentifier() != nil {
+		schema = name
+		name = unquote(ctx.DotIdentifier().Identifier().GetText())
+	}
+	return schema, name
+}
+
+func firstSelectItemList(tree antlr.Tree) *mysql.SelectItemListContext {
+	if list, ok := tree.(*mysql.SelectItemListContext); ok {
+		return list
+	}
+	for _, child := range tree.GetChildren() {
+		if list := firstSelectItemList(child); list != nil {
+			return list
+		}
+	}
+	return nil
+}
+
+func isColumnPath(text string) bool {
+	if len(text) == 0 {
+		return false
+	}
+	quoted := false
+	for _, c := range text {
+		switch {
+		case c == '`':
+			quoted = !quoted
+		case quoted, c == '.', c == '_', c == '$', c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c > 0x7f:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func findColumn(columns []*ColumnMetadata, name string) *ColumnMetadata {
+	for _, column := range columns {
+		if strings.EqualFold(column.Name, name) {
+			return column
+		}
+	}
+	return nil
+}
+
+func replaceColumn(table *TableMetadata, name string, column *ColumnMetadata) {
+	for i, existing := range table.Columns {
+		if strings.EqualFold(existing.Name, name) {
+			table.Columns[i] = column
+			return
+		}
+	}
+	table.Columns = append(table.Columns, column)
+}
+
+func removeTable(tables []*TableMetadata, name string) []*TableMetadata {
+	for i, table := range tables {
+		if strings.EqualFold(table.Name, name) {
+			return append(tables[:i], tables[i+1:]...)
+		}
+	}
+	return tables
+}
+
+func removeIndex(indexes []*IndexMetadata, name string) []*IndexMetadata {
+	for i, index := range indexes {
+		if strings.EqualFold(index.Name, name) {
+			return append(indexes[:i], indexes[i+1:]...)
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,126 @@
+package completion
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCatalogFromDDL(t *testing.T) {
+	a := require.New(t)
+	script, err := os.ReadFile("testdata/schema.sql")
+	a.NoError(err)
+
+	catalog, err := NewCatalogFromDDL(string(script), "")
+	a.NoError(err)
+
+	a.Equal([]string{"shop", "audit"}, catalog.ListSchemas())
+	a.Equal([]string{"customers", "orders", "order_archive"}, catalog.ListTables("shop"))
+	a.Equal([]string{"id", "name", "email"}, catalog.ListColumns("shop", "customers"))
+	a.Equal([]string{"id", "customer_id", "total", "created_at", "remark"}, catalog.ListColumns("shop", "orders"))
+	a.Equal([]string{"id", "customer_id", "total", "created_at", "remark"}, catalog.ListColumns("shop", "order_archive"))
+	a.Equal([]string{"PRIMARY", "idx_email"}, catalog.ListIndexes("shop", "customers"))
+	a.Equal([]string{"PRIMARY", "customer_id", "idx_created"}, catalog.ListIndexes("shop", "orders"))
+	a.Equal([]string{"order_totals", "recent_orders"}, catalog.ListViews("shop"))
+	a.Equal([]string{"customer_id", "total", "count(*)"}, catalog.ListColumns("shop", "order_totals"))
+	a.Equal([]string{"order_id", "placed"}, catalog.ListColumns("shop", "recent_orders"))
+	a.Equal([]string{"order_count"}, catalog.ListFunctions("shop"))
+	a.Equal([]string{"archive_orders", "recalculate_totals"}, catalog.ListProcedures("shop"))
+	a.Equal([]string{"orders_before_insert", "orders_after_update"}, catalog.ListTriggers("shop"))
+	a.Equal([]string{"nightly_cleanup"}, catalog.ListEvents("shop"))
+	a.Equal([]string{"id", "message"}, catalog.ListColumns("audit", "log"))
+
+	customers := catalog.Schema("shop").Table("customers")
+	a.Equal("InnoDB", customers.Engine)
+	a.Equal("Registered customers", customers.Comment)
+	a.Equal("bigint unsigned", customers.Columns[0].Type)
+	a.False(customers.Columns[0].Nullable)
+	a.Equal("Full name", customers.Columns[1].Comment)
+	a.True(customers.Columns[2].Nullable)
+
+	total := catalog.Schema("shop").Table("orders").Columns[2]
+	a.Equal("decimal(12,2)", total.Type)
+	a.Nil(total.Default)
+	createdAt := catalog.Schema("shop").Table("orders").Columns[3]
+	a.NotNil(createdAt.Default)
+	a.Equal("CURRENT_TIMESTAMP", *createdAt.Default)
+
+	function := catalog.Schema("shop").Routine("order_count", RoutineTypeFunction)
+	a.Equal("int", function.Returns)
+	a.Equal("bigint unsigned", function.Parameters[0].Type)
+	procedure := catalog.Schema("shop").Routine("archive_orders", RoutineTypeProcedure)
+	a.Equal("OUT", procedure.Parameters[1].Mode)
+
+	trigger := catalog.Schema("shop").Triggers[0]
+	a.Equal("orders", trigger.Table)
+	a.Equal("BEFORE", trigger.Timing)
+	a.Equal("INSERT", trigger.Event)
+	a.Equal("Remove old rows", catalog.Schema("shop").Events[0].Comment)
+	trigger = catalog.Schema("shop").Triggers[1]
+	a.Equal("AFTER", trigger.Timing)
+	a.Equal("UPDATE", trigger.Event)
+
+	_, err = NewCatalogFromDDL("CREATE TABLE (", "db")
+	a.Error(err)
+}
+
+func TestNewCatalogFromDDLErrors(t *testing.T) {
+	a := require.New(t)
+
+	// Statements which don't parse are skipped, the others are applied.
+	catalog, err := NewCatalogFromDDL("CREATE TABLE t1 (a int);\nCREATE TABLE (;\nCREATE TABLE t2 (b int);", "db")
+	a.Error(err)
+	a.Contains(err.Error(), "line 2:")
+	a.Equal([]string{"t1", "t2"}, catalog.ListTables("db"))
+}
+
+func TestNewCatalogFromDDLBodies(t *testing.T) {
+	a := require.New(t)
+
+	// Objects created or altered in routine, trigger and event bodies are not part of the catalog.
+	catalog, err := NewCatalogFromDDL(`CREATE TABLE t1 (a int);
+DELIMITER //
+CREATE PROCEDURE p1()
+BEGIN
+  CREATE TEMPORARY TABLE tmp (x int);
+  CREATE INDEX idx_a ON t1 (a);
+  ALTER TABLE t1 ADD COLUMN b int;
+END //
+CREATE FUNCTION f1() RETURNS int BEGIN CREATE TABLE f_tmp (x int); RETURN 1; END //
+CREATE TRIGGER tr1 AFTER INSERT ON t1 FOR EACH ROW BEGIN ALTER TABLE t1 DROP COLUMN a; END //
+CREATE EVENT e1 ON SCHEDULE EVERY 1 DAY DO CREATE TABLE e_tmp (x int) //
+DELIMITER ;
+CREATE TABLE t2 (c int);`, "db")
+	a.NoError(err)
+	a.Equal([]string{"t1", "t2"}, catalog.ListTables("db"))
+	a.Equal([]string{"a"}, catalog.ListColumns("db", "t1"))
+	a.Empty(catalog.ListIndexes("db", "t1"))
+	a.Equal([]string{"p1"}, catalog.ListProcedures("db"))
+	a.Equal([]string{"f1"}, catalog.ListFunctions("db"))
+	a.Equal([]string{"tr1"}, catalog.ListTriggers("db"))
+	a.Equal([]string{"e1"}, catalog.ListEvents("db"))
+
+	// CREATE TABLE IF NOT EXISTS leaves an existing table alone.
+	catalog, err = NewCatalogFromDDL("CREATE TABLE t1 (a int);\nCREATE TABLE IF NOT EXISTS t1 (b int);\nCREATE TABLE IF NOT EXISTS t2 (c int);", "db")
+	a.NoError(err)
+	a.Equal([]string{"a"}, catalog.ListColumns("db", "t1"))
+	a.Equal([]string{"c"}, catalog.ListColumns("db", "t2"))
+
+	// Quotes within quoted strings are doubled.
+	catalog, err = NewCatalogFromDDL("CREATE TABLE t1 (a int COMMENT 'it''s') COMMENT \"say \"\"hi\"\"\";", "db")
+	a.NoError(err)
+	table := catalog.Schema("db").Table("t1")
+	a.Equal("it's", table.Columns[0].Comment)
+	a.Equal(`say "hi"`, table.Comment)
+}
+
+func TestUnquote(t *testing.T) {
+	a := require.New(t)
+	a.Equal("a`b", unquote("`a``b`"))
+	a.Equal("it's", unquote("'it''s'"))
+	a.Equal(`say "hi"`, unquote(`"say ""hi"""`))
+	a.Equal("a''b", unquote(`"a''b"`))
+	a.Equal("name", unquote("name"))
+	a.Equal("`", unquote("`"))
+}
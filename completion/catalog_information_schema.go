@@ -0,0 +1,361 @@
+package completion
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InformationSchemaResult is the result set of a query against one of the INFORMATION_SCHEMA views.
+// Header holds the (upper or lower case) column names of the view, in the same order as the row values.
+// Only the columns used for the catalog need to be present, additional columns are ignored.
+// Values can be strings, byte slices (as returned by database/sql for text columns), numbers or nil for NULL.
+type InformationSchemaResult struct {
+	Header []string
+	Rows   [][]any
+}
+
+// InformationSchema bundles the INFORMATION_SCHEMA result sets a catalog is built from. Each of them is
+// optional, a nil result set simply contributes nothing.
+type InformationSchema struct {
+	Schemata   *InformationSchemaResult
+	Tables     *InformationSchemaResult
+	Columns    *InformationSchemaResult
+	Routines   *InformationSchemaResult
+	Parameters *InformationSchemaResult
+	Triggers   *InformationSchemaResult
+	Events     *InformationSchemaResult
+	Statistics *InformationSchemaResult
+}
+
+// NewCatalogFromInformationSchema builds a catalog from INFORMATION_SCHEMA result sets, e.g. as obtained by
+// `SELECT * FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA NOT IN ('mysql', 'sys', ...)`.
+func NewCatalogFromInformationSchema(is *InformationSchema) (*Catalog, error) {
+	catalog := &Catalog{}
+	getSchema := func(name string) *SchemaMetadata {
+		if schema := catalog.Schema(name); schema != nil {
+			return schema
+		}
+		schema := &SchemaMetadata{Name: name}
+		catalog.Schemas = append(catalog.Schemas, schema)
+		return schema
+	}
+
+	if err := is.Schemata.each("SCHEMATA", []string{"SCHEMA_NAME"}, func(row informationSchemaRow) {
+		getSchema(row.string("SCHEMA_NAME"))
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := is.Tables.each("TABLES", []string{"TABLE_SCHEMA", "TABLE_NAME"}, func(row informationSchemaRow) {
+		schema := getSchema(row.string("TABLE_SCHEMA"))
+		if strings.EqualFold(row.string("TABLE_TYPE"), "VIEW") {
+			schema.Views = append(schema.Views, &ViewMetadata{
+				Name: row.string("TABLE_NAME"),
+			})
+			return
+		}
+		schema.Tables = append(schema.Tables, &TableMetadata{
+			Name:    row.string("TABLE_NAME"),
+			Engine:  row.string("ENGINE"),
+			Comment: row.string("TABLE_COMMENT"),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	var columns []informationSchemaRow
+	if err := is.Columns.each("COLUMNS", []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME"}, func(row informationSchemaRow) {
+		columns = append(columns, row)
+	}); err != nil {
+		return nil, err
+	}
+	sortByPosition(columns, "ORDINAL_POSITION")
+	for _, row := range columns {
+		column := &ColumnMetadata{
+			Name:     row.string("COLUMN_NAME"),
+			Type:     row.string("COLUMN_TYPE"),
+			Nullable: strings.EqualFold(row.string("IS_NULLABLE"), "YES"),
+			Comment:  row.string("COLUMN_COMMENT"),
+		}
+		if len(column.Type) == 0 {
+			column.Type = row.string("DATA_TYPE")
+		}
+		if !row.isNull("COLUMN_DEFAULT") {
+			value := row.string("COLUMN_DEFAULT")
+			column.Default = &value
+		}
+
+		schema := getSchema(row.string("TABLE_SCHEMA"))
+		name := row.string("TABLE_NAME")
+		if table := schema.Table(name); table != nil {
+			table.Columns = append(table.Columns, column)
+		} else if view := schema.View(name); view != nil {
+			view.Columns = append(view.Columns, column)
+		} else {
+			// Columns without a TABLES entry, e.g. when only COLUMNS was exported.
+			schema.Tables = append(schema.Tables, &TableMetadata{
+				Name:    name,
+				Columns: []*ColumnMetadata{column},
+			})
+		}
+	}
+
+	if err := is.Routines.each("ROUTINES", []string{"ROUTINE_SCHEMA", "ROUTINE_NAME", "ROUTINE_TYPE"}, func(row informationSchemaRow) {
+		routine := &RoutineMetadata{
+			Name:    row.string("ROUTINE_NAME"),
+			Type:    RoutineType(strings.ToUpper(row.string("ROUTINE_TYPE"))),
+			Comment: row.string("ROUTINE_COMMENT"),
+		}
+		if routine.Type == RoutineTypeFunction {
+			routine.Returns = row.string("DTD_IDENTIFIER")
+		}
+		schema := getSchema(row.string("ROUTINE_SCHEMA"))
+		schema.Routines = append(schema.Routines, routine)
+	}); err != nil {
+		return nil, err
+	}
+
+	var parameters []informationSchemaRow
+	if err := is.Parameters.each("PARAMETERS", []string{"SPECIFIC_SCHEMA", "SPECIFIC_NAME", "ORDINAL_POSITION"}, func(row informationSchemaRow) {
+		parameters = append(parameters, row)
+	}); err != nil {
+		return nil, err
+	}
+	sortByPosition(parameters, "ORDINAL_POSITION")
+	for _, row := range parameters {
+		schema := catalog.Schema(row.string("SPECIFIC_SCHEMA"))
+		if schema == nil {
+			continue
+		}
+		routineType := RoutineType(strings.ToUpper(row.string("ROUTINE_TYPE")))
+		for _, routine := range schema.Routines {
+			if !strings.EqualFold(routine.Name, row.string("SPECIFIC_NAME")) || (len(routineType) > 0 && routine.Type != routineType) {
+				continue
+			}
+			if row.int("ORDINAL_POSITION") == 0 {
+				// Position 0 describes the return value of a stored function.
+				if len(routine.Returns) == 0 {
+					routine.Returns = row.string("DTD_IDENTIFIER")
+				}
+				continue
+			}
+			parameter := &ParameterMetadata{
+				Name: row.string("PARAMETER_NAME"),
+				Type: row.string("DTD_IDENTIFIER"),
+			}
+			if routine.Type == RoutineTypeProcedure {
+				parameter.Mode = strings.ToUpper(row.string("PARAMETER_MODE"))
+			}
+			routine.Parameters = append(routine.Parameters, parameter)
+		}
+	}
+
+	if err := is.Triggers.each("TRIGGERS", []string{"TRIGGER_SCHEMA", "TRIGGER_NAME"}, func(row informationSchemaRow) {
+		schema := getSchema(row.string("TRIGGER_SCHEMA"))
+		schema.Triggers = append(schema.Triggers, &TriggerMetadata{
+			Name:   row.string("TRIGGER_NAME"),
+			Table:  row.string("EVENT_OBJECT_TABLE"),
+			Timing: strings.ToUpper(row.string("ACTION_TIMING")),
+			Event:  strings.ToUpper(row.string("EVENT_MANIPULATION")),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := is.Events.each("EVENTS", []string{"EVENT_SCHEMA", "EVENT_NAME"}, func(row informationSchemaRow) {
+		schema := getSchema(row.string("EVENT_SCHEMA"))
+		schema.Events = append(schema.Events, &EventMetadata{
+			Name:    row.string("EVENT_NAME"),
+			Comment: row.string("EVENT_COMMENT"),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	var statistics []informationSchemaRow
+	if err := is.Statistics.each("STATISTICS", []string{"TABLE_SCHEMA", "TABLE_NAME", "INDEX_NAME"}, func(row informationSchemaRow) {
+		statistics = append(statistics, row)
+	}); err != nil {
+		return nil, err
+	}
+	sortByPosition(statistics, "SEQ_IN_INDEX")
+	for _, row := range statistics {
+		schema := catalog.Schema(row.string("TABLE_SCHEMA"))
+		if schema == nil {
+			continue
+		}
+		table := schema.Table(row.string("TABLE_NAME"))
+		if table == nil {
+			continue
+		}
+
+		part := row.string("COLUMN_NAME")
+		if row.isNull("COLUMN_NAME") {
+			// Functional key parts (8.0.13+) have no column but an expression.
+			part = row.string("EXPRESSION")
+		}
+
+		name := row.string("INDEX_NAME")
+		var index *IndexMetadata
+		for _, existing := range table.Indexes {
+			if existing.Name == name {
+				index = existing
+				break
+			}
+		}
+		if index == nil {
+			// Without a valid NON_UNIQUE value the index is not known to be unique.
+			nonUnique, ok := row.parseInt("NON_UNIQUE")
+			index = &IndexMetadata{
+				Name:   name,
+				Unique: ok && nonUnique == 0,
+			}
+			table.Indexes = append(table.Indexes, index)
+		}
+		index.Columns = append(index.Columns, part)
+	}
+
+	return catalog, nil
+}
+
+// informationSchemaFiles maps the InformationSchema result sets to the CSV files read by LoadInformationSchemaCSV.
+var informationSchemaFiles = []struct {
+	name   string
+	result func(*InformationSchema) **InformationSchemaResult
+}{
+	{"SCHEMATA", func(is *InformationSchema) **InformationSchemaResult { return &is.Schemata }},
+	{"TABLES", func(is *InformationSchema) **InformationSchemaResult { return &is.Tables }},
+	{"COLUMNS", func(is *InformationSchema) **InformationSchemaResult { return &is.Columns }},
+	{"ROUTINES", func(is *InformationSchema) **InformationSchemaResult { return &is.Routines }},
+	{"PARAMETERS", func(is *InformationSchema) **InformationSchemaResult { return &is.Parameters }},
+	{"TRIGGERS", func(is *InformationSchema) **InformationSchemaResult { return &is.Triggers }},
+	{"EVENTS", func(is *InformationSchema) **InformationSchemaResult { return &is.Events }},
+	{"STATISTICS", func(is *InformationSchema) **InformationSchemaResult { return &is.Statistics }},
+}
+
+// LoadInformationSchemaCSV reads the CSV exports SCHEMATA.csv, TABLES.csv, COLUMNS.csv, ROUTINES.csv,
+// PARAMETERS.csv, TRIGGERS.csv, EVENTS.csv and STATISTICS.csv from dir. Missing files are skipped.
+func LoadInformationSchemaCSV(dir string) (*InformationSchema, error) {
+	is := &InformationSchema{}
+	for _, file := range informationSchemaFiles {
+		path := filepath.Join(dir, file.name+".csv")
+		f, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open %q: %w", path, err)
+		}
+		result, err := ReadInformationSchemaCSV(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		*file.result(is) = result
+	}
+	return is, nil
+}
+
+// ReadInformationSchemaCSV reads a result set from CSV. The first record is the header. Fields containing
+// only \N (as written by SELECT ... INTO OUTFILE and mysqldump --tab) are read as NULL.
+func ReadInformationSchemaCSV(r io.Reader) (*InformationSchemaResult, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing CSV header")
+	}
+
+	result := &InformationSchemaResult{Header: records[0]}
+	for _, record := range records[1:] {
+		row := make([]any, len(record))
+		for i, field := range record {
+			if field != `\N` {
+				row[i] = field
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+type informationSchemaRow struct {
+	index  map[string]int
+	values []any
+}
+
+// each calls fn for every row of the result set, after checking that the required columns are present.
+func (r *InformationSchemaResult) each(view string, required []string, fn func(row informationSchemaRow)) error {
+	if r == nil {
+		return nil
+	}
+
+	index := make(map[string]int)
+	for i, name := range r.Header {
+		index[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	for _, name := range required {
+		if _, exists := index[name]; !exists {
+			return fmt.Errorf("INFORMATION_SCHEMA.%s result is missing column %s", view, name)
+		}
+	}
+
+	for _, values := range r.Rows {
+		fn(informationSchemaRow{index: index, values: values})
+	}
+	return nil
+}
+
+func (r informationSchemaRow) value(column string) any {
+	i, exists := r.index[column]
+	if !exists || i >= len(r.values) {
+		return nil
+	}
+	return r.values[i]
+}
+
+func (r informationSchemaRow) isNull(column string) bool {
+	return r.value(column) == nil
+}
+
+func (r informationSchemaRow) string(column string) string {
+	switch value := r.value(column).(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func (r informationSchemaRow) int(column string) int {
+	value, _ := r.parseInt(column)
+	return value
+}
+
+// parseInt returns the integer value of the column, ok is false if the column is missing, NULL or not a number.
+func (r informationSchemaRow) parseInt(column string) (value int, ok bool) {
+	value, err := strconv.Atoi(strings.TrimSpace(r.string(column)))
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func sortByPosition(rows []informationSchemaRow, column string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].int(column) < rows[j].int(column)
+	})
+}
@@ -0,0 +1,105 @@
+package completion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCatalogFromInformationSchema(t *testing.T) {
+	a := require.New(t)
+	is, err := LoadInformationSchemaCSV("testdata/information_schema")
+	a.NoError(err)
+	catalog, err := NewCatalogFromInformationSchema(is)
+	a.NoError(err)
+
+	a.Equal([]string{"shop", "empty"}, catalog.ListSchemas())
+	a.Equal([]string{"customers", "orders"}, catalog.ListTables("shop"))
+	a.Equal([]string{"order_totals"}, catalog.ListViews("shop"))
+	a.Equal([]string{"id", "customer_id", "total"}, catalog.ListColumns("shop", "orders"))
+	a.Equal([]string{"customer_id", "total"}, catalog.ListColumns("shop", "order_totals"))
+	a.Equal([]string{"order_count"}, catalog.ListFunctions("shop"))
+	a.Equal([]string{"archive_orders"}, catalog.ListProcedures("shop"))
+	a.Equal([]string{"orders_before_insert"}, catalog.ListTriggers("shop"))
+	a.Equal([]string{"nightly_cleanup"}, catalog.ListEvents("shop"))
+	a.Equal([]string{"PRIMARY", "idx_email", "idx_name"}, catalog.ListIndexes("shop", "customers"))
+	a.True(catalog.Schema("shop").Table("customers").Indexes[1].Unique)
+	a.False(catalog.Schema("shop").Table("customers").Indexes[2].Unique)
+
+	customers := catalog.Schema("shop").Table("customers")
+	a.Equal("InnoDB", customers.Engine)
+	a.Equal("Registered customers", customers.Comment)
+	a.True(customers.Columns[2].Nullable)
+	a.Equal("Full name", customers.Columns[1].Comment)
+
+	total := catalog.Schema("shop").Table("orders").Columns[2]
+	a.Equal("decimal(10,2)", total.Type)
+	a.Equal("0.00", *total.Default)
+	a.Nil(catalog.Schema("shop").Table("orders").Columns[0].Default)
+
+	index := catalog.Schema("shop").Table("orders").Indexes[1]
+	a.Equal("idx_customer_total", index.Name)
+	a.False(index.Unique)
+	a.Equal([]string{"customer_id", "(`total` * 2)"}, index.Columns)
+
+	function := catalog.Schema("shop").Routine("order_count", RoutineTypeFunction)
+	a.Equal("int", function.Returns)
+	a.Len(function.Parameters, 1)
+	a.Equal("", function.Parameters[0].Mode)
+	procedure := catalog.Schema("shop").Routine("archive_orders", RoutineTypeProcedure)
+	a.Equal("cutoff", procedure.Parameters[0].Name)
+	a.Equal("OUT", procedure.Parameters[1].Mode)
+}
+
+func TestNewCatalogFromInformationSchemaRows(t *testing.T) {
+	a := require.New(t)
+	catalog, err := NewCatalogFromInformationSchema(&InformationSchema{
+		Columns: &InformationSchemaResult{
+			Header: []string{"table_schema", "table_name", "column_name", "column_type", "is_nullable", "column_default"},
+			Rows: [][]any{
+				{[]byte("db"), []byte("t"), []byte("a"), []byte("int"), []byte("YES"), nil},
+				{"db", "t", "b", "text", "NO", "x"},
+			},
+		},
+	})
+	a.NoError(err)
+	a.Equal([]string{"a", "b"}, catalog.ListColumns("db", "t"))
+	a.True(catalog.Schema("db").Table("t").Columns[0].Nullable)
+
+	// Indexes are only unique if NON_UNIQUE is present and 0.
+	catalog, err = NewCatalogFromInformationSchema(&InformationSchema{
+		Tables: &InformationSchemaResult{
+			Header: []string{"TABLE_SCHEMA", "TABLE_NAME"},
+			Rows:   [][]any{{"db", "t"}},
+		},
+		Statistics: &InformationSchemaResult{
+			Header: []string{"TABLE_SCHEMA", "TABLE_NAME", "INDEX_NAME", "COLUMN_NAME"},
+			Rows:   [][]any{{"db", "t", "idx_a", "a"}},
+		},
+	})
+	a.NoError(err)
+	a.Equal([]string{"idx_a"}, catalog.ListIndexes("db", "t"))
+	a.False(catalog.Schema("db").Table("t").Indexes[0].Unique)
+	catalog, err = NewCatalogFromInformationSchema(&InformationSchema{
+		Tables: &InformationSchemaResult{
+			Header: []string{"TABLE_SCHEMA", "TABLE_NAME"},
+			Rows:   [][]any{{"db", "t"}},
+		},
+		Statistics: &InformationSchemaResult{
+			Header: []string{"TABLE_SCHEMA", "TABLE_NAME", "NON_UNIQUE", "INDEX_NAME", "COLUMN_NAME"},
+			Rows:   [][]any{{"db", "t", "no", "idx_a", "a"}, {"db", "t", int64(0), "idx_b", "b"}},
+		},
+	})
+	a.NoError(err)
+	a.False(catalog.Schema("db").Table("t").Indexes[0].Unique)
+	a.True(catalog.Schema("db").Table("t").Indexes[1].Unique)
+
+	_, err = NewCatalogFromInformationSchema(&InformationSchema{
+		Tables: &InformationSchemaResult{Header: []string{"TABLE_NAME"}},
+	})
+	a.Error(err)
+
+	_, err = ReadInformationSchemaCSV(strings.NewReader(""))
+	a.Error(err)
+}
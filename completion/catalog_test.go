@@ -0,0 +1,86 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	a := require.New(t)
+
+	for _, path := range []string{"testdata/catalog.yaml", "testdata/catalog.json"} {
+		catalog, err := LoadCatalog(path)
+		a.NoError(err, path)
+
+		a.Equal([]string{"shop", "audit"}, catalog.ListSchemas(), path)
+		a.Equal([]string{"customers", "orders"}, catalog.ListTables("shop"), path)
+		a.Equal([]string{"order_totals"}, catalog.ListViews("SHOP"), path)
+		a.Equal([]string{"id", "customer_id", "total", "created_at"}, catalog.ListColumns("shop", "orders"), path)
+		a.Equal([]string{"customer_id", "total"}, catalog.ListColumns("shop", "order_totals"), path)
+		a.Equal([]string{"PRIMARY", "idx_email"}, catalog.ListIndexes("shop", "customers"), path)
+		a.Equal([]string{"order_count"}, catalog.ListFunctions("shop"), path)
+		a.Equal([]string{"archive_orders"}, catalog.ListProcedures("shop"), path)
+		a.Equal([]string{"orders_before_insert"}, catalog.ListTriggers("shop"), path)
+		a.Equal([]string{"nightly_cleanup"}, catalog.ListEvents("shop"), path)
+		a.Equal([]string{"InnoDB", "MyISAM", "MEMORY"}, catalog.ListEngines(), path)
+		a.Nil(catalog.ListTables("unknown"), path)
+
+		column := catalog.Schema("shop").Table("orders").Columns[2]
+		a.Equal("decimal(10,2)", column.Type, path)
+		a.NotNil(column.Default, path)
+		a.Equal("0.00", *column.Default, path)
+	}
+
+	_, err := NewCatalogFromYAML([]byte("schemas: {"))
+	a.Error(err)
+}
+
+func TestCatalogCompletion(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+
+	parser, caretLine, caretOffset := parseCaret("SELECT | FROM orders o", 0)
+	result := GetCodeCompletionList(caretLine, caretOffset, "shop", true, parser, catalog)
+
+	a.Contains(result, "7(customer_id)")
+	a.Contains(result, "7(created_at)")
+	a.Contains(result, "3(customers)")
+	a.Contains(result, "6(order_totals)")
+	a.NotContains(result, "7(message)")
+}
+
+func TestCompletionItems(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+
+	parser, caretLine, caretOffset := parseCaret("SELECT | FROM orders o", 0)
+	items := GetCompletionItems(caretLine, caretOffset, "shop", true, parser, catalog)
+
+	find := func(kind AutoCompletionImageType, label string) CompletionItem {
+		for _, item := range items {
+			if item.Kind == kind && item.Label == label {
+				return item
+			}
+		}
+		a.Failf("missing completion item", "%d(%s)", kind, label)
+		return CompletionItem{}
+	}
+
+	total := find(AutoCompletionImageTypeColumn, "total")
+	a.Equal("total", total.InsertText)
+	a.Equal("decimal(10,2)", total.Detail)
+	a.Equal("Column of shop.orders\nNot null\nDefault: 0.00", total.Documentation)
+
+	a.Equal("shop.customers", find(AutoCompletionImageTypeTable, "customers").Detail)
+	a.Equal("alias for orders", find(AutoCompletionImageTypeTable, "o").Detail)
+
+	for i := 1; i < len(items); i++ {
+		a.Less(items[i-1].SortText, items[i].SortText)
+	}
+
+	routine := catalog.Schema("shop").Routine("order_count", RoutineTypeFunction)
+	a.Equal("order_count(customer bigint unsigned) RETURNS int", routine.Signature())
+}
@@ -0,0 +1,210 @@
+package completion
+
+import (
+	"io"
+	"time"
+
+	mysql "github.com/bytebase/mysql-parser"
+
+	"github.com/rebelice/mysql-completer/c3"
+)
+
+var (
+	// Tokens which are never offered as candidates.
+	defaultIgnoredTokens = map[int]bool{
+		mysql.MySQLParserEOF:                      true,
+		mysql.MySQLLexerEQUAL_OPERATOR:            true,
+		mysql.MySQLLexerASSIGN_OPERATOR:           true,
+		mysql.MySQLLexerNULL_SAFE_EQUAL_OPERATOR:  true,
+		mysql.MySQLLexerGREATER_OR_EQUAL_OPERATOR: true,
+		mysql.MySQLLexerGREATER_THAN_OPERATOR:     true,
+		mysql.MySQLLexerLESS_OR_EQUAL_OPERATOR:    true,
+		mysql.MySQLLexerLESS_THAN_OPERATOR:        true,
+		mysql.MySQLLexerNOT_EQUAL_OPERATOR:        true,
+		mysql.MySQLLexerNOT_EQUAL2_OPERATOR:       true,
+		mysql.MySQLLexerPLUS_OPERATOR:             true,
+		mysql.MySQLLexerMINUS_OPERATOR:            true,
+		mysql.MySQLLexerMULT_OPERATOR:             true,
+		mysql.MySQLLexerDIV_OPERATOR:              true,
+		mysql.MySQLLexerMOD_OPERATOR:              true,
+		mysql.MySQLLexerLOGICAL_NOT_OPERATOR:      true,
+		mysql.MySQLLexerBITWISE_NOT_OPERATOR:      true,
+		mysql.MySQLLexerSHIFT_LEFT_OPERATOR:       true,
+		mysql.MySQLLexerSHIFT_RIGHT_OPERATOR:      true,
+		mysql.MySQLLexerLOGICAL_AND_OPERATOR:      true,
+		mysql.MySQLLexerBITWISE_AND_OPERATOR:      true,
+		mysql.MySQLLexerBITWISE_XOR_OPERATOR:      true,
+		mysql.MySQLLexerLOGICAL_OR_OPERATOR:       true,
+		mysql.MySQLLexerBITWISE_OR_OPERATOR:       true,
+		mysql.MySQLLexerDOT_SYMBOL:                true,
+		mysql.MySQLLexerCOMMA_SYMBOL:              true,
+		mysql.MySQLLexerSEMICOLON_SYMBOL:          true,
+		mysql.MySQLLexerCOLON_SYMBOL:              true,
+		mysql.MySQLLexerOPEN_PAR_SYMBOL:           true,
+		mysql.MySQLLexerCLOSE_PAR_SYMBOL:          true,
+		mysql.MySQLLexerOPEN_CURLY_SYMBOL:         true,
+		mysql.MySQLLexerCLOSE_CURLY_SYMBOL:        true,
+		mysql.MySQLLexerUNDERLINE_SYMBOL:          true,
+		mysql.MySQLLexerAT_SIGN_SYMBOL:            true,
+		mysql.MySQLLexerAT_AT_SIGN_SYMBOL:         true,
+		mysql.MySQLLexerNULL2_SYMBOL:              true,
+		mysql.MySQLLexerPARAM_MARKER:              true,
+		mysql.MySQLLexerCONCAT_PIPES_SYMBOL:       true,
+		mysql.MySQLLexerAT_TEXT_SUFFIX:            true,
+		mysql.MySQLLexerBACK_TICK_QUOTED_ID:       true,
+		mysql.MySQLLexerSINGLE_QUOTED_TEXT:        true,
+		mysql.MySQLLexerDOUBLE_QUOTED_TEXT:        true,
+		mysql.MySQLLexerNCHAR_TEXT:                true,
+		mysql.MySQLLexerUNDERSCORE_CHARSET:        true,
+		mysql.MySQLLexerIDENTIFIER:                true,
+		mysql.MySQLLexerINT_NUMBER:                true,
+		mysql.MySQLLexerLONG_NUMBER:               true,
+		mysql.MySQLLexerULONGLONG_NUMBER:          true,
+		mysql.MySQLLexerDECIMAL_NUMBER:            true,
+		mysql.MySQLLexerBIN_NUMBER:                true,
+		mysql.MySQLLexerHEX_NUMBER:                true,
+	}
+
+	// Rules for which candidates are collected instead of the tokens they consist of.
+	defaultPreferredRules = map[int]bool{
+		mysql.MySQLParserRULE_schemaRef:            true,
+		mysql.MySQLParserRULE_tableRef:             true,
+		mysql.MySQLParserRULE_tableRefWithWildcard: true,
+		mysql.MySQLParserRULE_filterTableRef:       true,
+		mysql.MySQLParserRULE_columnRef:            true,
+		mysql.MySQLParserRULE_columnInternalRef:    true,
+		mysql.MySQLParserRULE_tableWild:            true,
+		mysql.MySQLParserRULE_functionRef:          true,
+		mysql.MySQLParserRULE_functionCall:         true,
+		mysql.MySQLParserRULE_runtimeFunctionCall:  true,
+		mysql.MySQLParserRULE_triggerRef:           true,
+		mysql.MySQLParserRULE_viewRef:              true,
+		mysql.MySQLParserRULE_procedureRef:         true,
+		mysql.MySQLParserRULE_logfileGroupRef:      true,
+		mysql.MySQLParserRULE_tablespaceRef:        true,
+		mysql.MySQLParserRULE_engineRef:            true,
+		mysql.MySQLParserRULE_collationName:        true,
+		mysql.MySQLParserRULE_charsetName:          true,
+		mysql.MySQLParserRULE_eventRef:             true,
+		mysql.MySQLParserRULE_indexRef:             true,
+		mysql.MySQLParserRULE_serverRef:            true,
+		mysql.MySQLParserRULE_user:                 true,
+		mysql.MySQLParserRULE_userVariable:         true,
+		mysql.MySQLParserRULE_systemVariable:       true,
+		mysql.MySQLParserRULE_labelRef:             true,
+		mysql.MySQLParserRULE_setSystemVariable:    true,
+		mysql.MySQLParserRULE_parameterName:        true,
+		mysql.MySQLParserRULE_procedureName:        true,
+		mysql.MySQLParserRULE_identifier:           true,
+		mysql.MySQLParserRULE_labelIdentifier:      true,
+	}
+
+	// Alternative spellings offered along with keywords.
+	defaultSynonyms = map[int][]string{
+		mysql.MySQLLexerCHAR_SYMBOL:         {"CHARACTER"},
+		mysql.MySQLLexerNOW_SYMBOL:          {"CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP"},
+		mysql.MySQLLexerDAY_SYMBOL:          {"DAYOFMONTH", "SQL_TSI_DAY"},
+		mysql.MySQLLexerDECIMAL_SYMBOL:      {"DEC"},
+		mysql.MySQLLexerDISTINCT_SYMBOL:     {"DISTINCTROW"},
+		mysql.MySQLLexerCOLUMNS_SYMBOL:      {"FIELDS"},
+		mysql.MySQLLexerFLOAT_SYMBOL:        {"FLOAT4"},
+		mysql.MySQLLexerDOUBLE_SYMBOL:       {"FLOAT8"},
+		mysql.MySQLLexerINT_SYMBOL:          {"INTEGER", "INT4"},
+		mysql.MySQLLexerRELAY_THREAD_SYMBOL: {"IO_THREAD"},
+		mysql.MySQLLexerSUBSTRING_SYMBOL:    {"MID", "SUBSTR"},
+		mysql.MySQLLexerMID_SYMBOL:          {"MEDIUMINT"},
+		mysql.MySQLLexerMEDIUMINT_SYMBOL:    {"MIDDLEINT", "INT3"},
+		mysql.MySQLLexerNDBCLUSTER_SYMBOL:   {"NDB"},
+		mysql.MySQLLexerREGEXP_SYMBOL:       {"RLIKE"},
+		mysql.MySQLLexerDATABASE_SYMBOL:     {"SCHEMA"},
+		mysql.MySQLLexerDATABASES_SYMBOL:    {"SCHEMAS"},
+		mysql.MySQLLexerUSER_SYMBOL:         {"SESSION_USER"},
+		mysql.MySQLLexerSTD_SYMBOL:          {"STDDEV", "STDDEV"},
+		mysql.MySQLLexerVARCHAR_SYMBOL:      {"VARCHARACTER"},
+		mysql.MySQLLexerVARIANCE_SYMBOL:     {"VAR_POP"},
+		mysql.MySQLLexerTINYINT_SYMBOL:      {"INT1"},
+		mysql.MySQLLexerSMALLINT_SYMBOL:     {"INT2"},
+		mysql.MySQLLexerBIGINT_SYMBOL:       {"INT8"},
+		mysql.MySQLLexerSECOND_SYMBOL:       {"SQL_TSI_SECOND"},
+		mysql.MySQLLexerMINUTE_SYMBOL:       {"SQL_TSI_MINUTE"},
+		mysql.MySQLLexerHOUR_SYMBOL:         {"SQL_TSI_HOUR"},
+		mysql.MySQLLexerWEEK_SYMBOL:         {"SQL_TSI_WEEK"},
+		mysql.MySQLLexerMONTH_SYMBOL:        {"SQL_TSI_MONTH"},
+		mysql.MySQLLexerQUARTER_SYMBOL:      {"SQL_TSI_QUARTER"},
+		mysql.MySQLLexerYEAR_SYMBOL:         {"SQL_TSI_YEAR"},
+	}
+)
+
+// defaultCompleter is used by the package level completion functions.
+var defaultCompleter = NewCompleter()
+
+// Completer determines completion candidates. It owns the configuration of the candidate collection and caches
+// the follow sets computed from the grammar, so that repeated requests get faster after the first one. A Completer
+// is meant to be long-lived and shared, the configuration must not be changed once it is in use.
+//
+// A Completer is safe for concurrent use. All state of a request is kept per request, but the parser passed in
+// belongs to that request: its token stream is read and repositioned, so it must not be used elsewhere meanwhile.
+type Completer struct {
+	// IgnoredTokens are never offered as candidates.
+	IgnoredTokens map[int]bool
+	// PreferredRules are rules for which candidates are collected instead of the tokens they consist of.
+	PreferredRules map[int]bool
+	// Synonyms are alternative spellings offered along with keywords.
+	Synonyms map[int][]string
+	// ServerVersion is the version of the MySQL server completion is done for, e.g. 80032 for 8.0.32 (see
+	// ServerVersion57 and ServerVersion80). Keywords and functions which only exist in other versions, like LATERAL
+	// or the window functions before 8.0, are not offered. 0 offers everything the grammar knows.
+	ServerVersion int
+	// SQLMode is the sql_mode scripts are split and lexed in by CompleteScript, SplitStatements, Hover and
+	// SignatureHelp (see ParseSQLMode). It doesn't apply to parsers passed in, e.g. to GetCompletionItems: their
+	// tokens are lexed already, so the mode of their lexer is used (see NewLexer), the default mode for a plain
+	// MySQLLexer.
+	SQLMode SQLMode
+	// CaretEncoding is the unit caret columns and the columns of returned positions are counted in. It defaults to
+	// code points, editors speaking the Language Server Protocol usually need CaretEncodingUTF16.
+	CaretEncoding CaretEncoding
+	// Format determines the case of keywords, function names and database object names.
+	Format FormatOptions
+	// Usage holds how often items have been picked before, frequently used items are ranked higher (see RankItems).
+	Usage UsageHints
+	// MaxStates limits the number of grammar (ATN) states explored per request, 0 means no limit.
+	MaxStates int
+	// MaxDuration limits the time spent on collecting the candidates per request, 0 means no limit.
+	MaxDuration time.Duration
+	// Trace receives a trace of the grammar (ATN) walk of every request, for diagnosing missing or unexpected
+	// candidates. The traces of concurrent requests are interleaved.
+	Trace io.Writer
+
+	followSets *c3.FollowSetsCache
+}
+
+// NewCompleter creates a completer with the default configuration.
+func NewCompleter() *Completer {
+	completer := &Completer{
+		IgnoredTokens:  make(map[int]bool),
+		PreferredRules: make(map[int]bool),
+		Synonyms:       make(map[int][]string),
+		followSets:     c3.NewFollowSetsCache(),
+	}
+	for token := range defaultIgnoredTokens {
+		completer.IgnoredTokens[token] = true
+	}
+	for rule := range defaultPreferredRules {
+		completer.PreferredRules[rule] = true
+	}
+	for token, synonyms := range defaultSynonyms {
+		completer.Synonyms[token] = synonyms
+	}
+	return completer
+}
+
+func (c *Completer) newCodeCompletionCore(parser *mysql.MySQLParser) *c3.CodeCompletionCore {
+	core := c3.NewCodeCompletionCore(parser)
+	core.IgnoredTokens = c.IgnoredTokens
+	core.PreferredRules = c.PreferredRules
+	core.FollowSetsCache = c.followSets
+	core.MaxStates = c.MaxStates
+	core.MaxDuration = c.MaxDuration
+	core.Trace = c.Trace
+	return core
+}
@@ -1,10 +1,14 @@
 package completion
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/antlr4-go/antlr/v4"
 	mysql "github.com/bytebase/mysql-parser"
@@ -59,15 +63,40 @@ func TestCompleter(t *testing.T) {
 	}
 }
 
+// catchCaret removes the caret marker from a single line and returns the caret column in code points.
 func catchCaret(s string) (string, int) {
+	column := 0
 	for i, c := range s {
 		if c == '|' {
-			return s[:i] + s[i+1:], i
+			return s[:i] + s[i+1:], column
 		}
+		column++
 	}
 	return s, -1
 }
 
+// parseCaret removes the caret marker from input, which may have multiple lines, and returns a parser for the
+// text lexed in the given sql_mode, with the caret line (one-based) and column. Syntax errors are not reported.
+func parseCaret(input string, mode SQLMode) (parser *mysql.MySQLParser, caretLine int, caretOffset int) {
+	text, caretLine, caretOffset := catchCaretPosition(input)
+	lexer := NewLexer(antlr.NewInputStream(text), mode)
+	lexer.RemoveErrorListeners()
+	parser = mysql.NewMySQLParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	parser.RemoveErrorListeners()
+	return parser, caretLine, caretOffset
+}
+
+// completionLabels returns the completion items for input in their "kind(label)" form, with "db" as the default
+// schema of testMetadata.
+func completionLabels(completer *Completer, input string) []string {
+	parser, caretLine, caretOffset := parseCaret(input, 0)
+	var result []string
+	for _, item := range completer.GetCompletionItems(caretLine, caretOffset, "db", true, parser, testMetadata{}) {
+		result = append(result, item.String())
+	}
+	return result
+}
+
 type candidatesTest struct {
 	Input string
 	Want  []string
@@ -77,7 +106,7 @@ func TestCandidates(t *testing.T) {
 	tests := []candidatesTest{}
 
 	const (
-		record = true
+		record = false
 	)
 	var (
 		filepath = "testdata/data.yaml"
@@ -93,13 +122,8 @@ func TestCandidates(t *testing.T) {
 	a.NoError(yaml.Unmarshal(byteValue, &tests))
 
 	for i, t := range tests {
-		text, caretOffset := catchCaret(t.Input)
-		input := antlr.NewInputStream(text)
-		lexer := mysql.NewMySQLLexer(input)
-		tokens := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
-		parser := mysql.NewMySQLParser(tokens)
-		parser.RemoveErrorListeners()
-		result := GetCodeCompletionList(1, caretOffset, "db", true, parser)
+		parser, caretLine, caretOffset := parseCaret(t.Input, 0)
+		result := GetCodeCompletionList(caretLine, caretOffset, "db", true, parser, testMetadata{})
 		if record {
 			tests[i].Want = result
 		} else {
@@ -114,3 +138,233 @@ func TestCandidates(t *testing.T) {
 		a.NoError(err)
 	}
 }
+
+func TestMultiStatementScript(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     []string
+		dontWant []string
+	}{
+		{
+			input:    "SELECT * FROM table1 t1;\nSELECT | FROM table2 t2;\nSELECT * FROM table3 t3;",
+			want:     []string{"3(t2)", "7(c1)"},
+			dontWant: []string{"3(t1)", "3(t3)", "7(c2)"},
+		},
+		{
+			// Syntax errors in other statements don't matter.
+			input:    "SELEC garbage FROM table3;\nSELECT | FROM table2 t2",
+			want:     []string{"3(t2)", "7(c1)"},
+			dontWant: []string{"7(c2)"},
+		},
+		{
+			input: "SELECT * FROM table1 t1;\nSELECT * FROM table2 t2 WHERE |",
+			want:  []string{"3(t2)", "1(NOT)"},
+		},
+		{
+			input: "SELECT 1;\n|",
+			want:  []string{"1(SELECT)", "1(CREATE)"},
+		},
+	}
+
+	a := require.New(t)
+	for _, test := range tests {
+		result := completionLabels(defaultCompleter, test.input)
+		for _, want := range test.want {
+			a.Contains(result, want, test.input)
+		}
+		for _, dontWant := range test.dontWant {
+			a.NotContains(result, dontWant, test.input)
+		}
+	}
+}
+
+func TestCompleterReuse(t *testing.T) {
+	a := require.New(t)
+	completer := NewCompleter()
+	delete(completer.Synonyms, mysql.MySQLLexerDATABASE_SYMBOL)
+	completer.IgnoredTokens[mysql.MySQLLexerTABLE_SYMBOL] = true
+
+	first := completionLabels(completer, "CREATE |")
+	a.NotZero(completer.followSets.Len())
+	a.Equal(first, completionLabels(completer, "CREATE |"))
+	a.Contains(first, "1(DATABASE)")
+	a.NotContains(first, "1(SCHEMA)")
+	a.NotContains(first, "1(TABLE)")
+
+	// The default completer isn't affected by the configuration changes.
+	defaults := completionLabels(defaultCompleter, "CREATE |")
+	a.Contains(defaults, "1(SCHEMA)")
+	a.Contains(defaults, "1(TABLE)")
+}
+
+func TestCompletionBudget(t *testing.T) {
+	a := require.New(t)
+	complete := func(ctx context.Context, completer *Completer, input string) *CompletionResult {
+		parser, caretLine, caretOffset := parseCaret(input, 0)
+		return completer.GetCompletionResultContext(ctx, caretLine, caretOffset, "db", true, parser, testMetadata{})
+	}
+
+	input := "SELECT | FROM table1"
+	full := complete(context.Background(), NewCompleter(), input)
+	a.False(full.Incomplete)
+
+	limited := NewCompleter()
+	limited.MaxStates = 50
+	result := complete(context.Background(), limited, input)
+	a.True(result.Incomplete)
+	a.Less(len(result.Items), len(full.Items))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = complete(ctx, NewCompleter(), input)
+	a.True(result.Incomplete)
+
+	// A pathological, deeply nested expression.
+	timed := NewCompleter()
+	timed.MaxDuration = time.Millisecond
+	nested := "SELECT " + strings.Repeat("(1 + ", 200) + "|"
+	start := time.Now()
+	result = complete(context.Background(), timed, nested)
+	a.True(result.Incomplete)
+	a.Less(time.Since(start), time.Second)
+}
+
+func TestCompleterTrace(t *testing.T) {
+	a := require.New(t)
+	var trace strings.Builder
+	completer := NewCompleter()
+	completer.Trace = &trace
+	completionLabels(completer, "SELECT * FROM |")
+	a.True(strings.HasPrefix(trace.String(), "enter query (state "))
+	a.Contains(trace.String(), "collected rule tableRef, path: [query > simpleStatement > selectStatement > ")
+}
+
+func TestRulePath(t *testing.T) {
+	a := require.New(t)
+	parser, caretLine, caretOffset := parseCaret("SELECT | FROM table1", 0)
+	paths := make(map[string]string)
+	for _, item := range GetCompletionItems(caretLine, caretOffset, "db", true, parser, testMetadata{}) {
+		paths[item.String()] = strings.Join(item.RulePath, " > ")
+	}
+
+	selectItem := "query > simpleStatement > selectStatement > queryExpression > queryExpressionBody > queryPrimary > " +
+		"querySpecification > selectItemList > selectItem > expr > boolPri > predicate > bitExpr > simpleExpr"
+	a.Equal(selectItem+" > columnRef", paths["7(c0)"])
+	a.Equal(selectItem+" > columnRef", paths["3(table1)"])
+	a.Equal(selectItem+" > runtimeFunctionCall", paths["5(concat())"])
+	a.True(strings.HasSuffix(paths["1(COUNT)"], " > simpleExpr > sumExpr"))
+	a.True(strings.HasSuffix(paths["1(DISTINCT)"], " > querySpecification > selectOption > querySpecOption"))
+}
+
+func TestInternalReferences(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: "ALTER TABLE customers DROP INDEX |", want: []string{"16(PRIMARY)", "16(idx_email)"}},
+		{input: "ALTER TABLE shop.customers RENAME INDEX | TO idx", want: []string{"16(PRIMARY)", "16(idx_email)"}},
+		{input: "DROP INDEX | ON shop.customers", want: []string{"16(PRIMARY)", "16(idx_email)"}},
+		{input: "DROP INDEX |", want: nil},
+		{input: "ALTER TABLE customers DROP COLUMN |", want: []string{"7(email)", "7(id)", "7(name)"}},
+		{input: "ALTER TABLE orders MODIFY |", want: []string{"7(created_at)", "7(customer_id)", "7(id)", "7(total)"}},
+		{input: "CREATE PROCEDURE p() outer_block: BEGIN l1: LOOP ITERATE |", want: []string{"20(l1)", "20(outer_block)"}},
+		{input: "DROP SERVER |", want: nil},
+	}
+
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+	for _, test := range tests {
+		text, caretOffset := catchCaret(test.input)
+		var result []string
+		for _, item := range CompleteScript(text, 1, caretOffset, "shop", true, catalog).Items {
+			if item.Kind != AutoCompletionImageTypeKeyword {
+				result = append(result, item.String())
+			}
+		}
+		a.Equal(test.want, result, test.input)
+	}
+}
+
+func BenchmarkCompleter(b *testing.B) {
+	completer := NewCompleter()
+	for i := 0; i < b.N; i++ {
+		completionLabels(completer, "SELECT a, b FROM table1 t1 JOIN table2 t2 ON t1.c0 = t2.| WHERE t1.c0 > 1")
+	}
+}
+
+// catchCaretPosition works like catchCaret for text with multiple lines. It returns the caret line (one-based)
+// and column.
+func catchCaretPosition(s string) (text string, line int, column int) {
+	line, column = 1, 0
+	for i, c := range s {
+		switch c {
+		case '|':
+			return s[:i] + s[i+1:], line, column
+		case '\n':
+			line++
+			column = 0
+		default:
+			column++
+		}
+	}
+	return s, -1, -1
+}
+
+// testMetadata is a fixed catalog with a schema "db", tables table0..table4 and views view0..view4.
+// Table tableN has the columns c0..c(N-1).
+type testMetadata struct{}
+
+func (testMetadata) ListSchemas() []string {
+	return []string{"db"}
+}
+
+func (testMetadata) ListTables(schema string) []string {
+	if schema != "db" {
+		return nil
+	}
+	var result []string
+	for i := 0; i < 5; i++ {
+		result = append(result, fmt.Sprintf("table%d", i))
+	}
+	return result
+}
+
+func (testMetadata) ListViews(schema string) []string {
+	if schema != "db" {
+		return nil
+	}
+	var result []string
+	for i := 0; i < 5; i++ {
+		result = append(result, fmt.Sprintf("view%d", i))
+	}
+	return result
+}
+
+func (testMetadata) ListColumns(schema, table string) []string {
+	if schema != "db" || len(table) == 0 {
+		return nil
+	}
+	id, err := strconv.Atoi(table[len(table)-1:])
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for i := 0; i < id; i++ {
+		result = append(result, fmt.Sprintf("c%d", i))
+	}
+	return result
+}
+
+func (testMetadata) ListIndexes(string, string) []string { return nil }
+func (testMetadata) ListFunctions(string) []string       { return nil }
+func (testMetadata) ListProcedures(string) []string      { return nil }
+func (testMetadata) ListTriggers(string) []string        { return nil }
+func (testMetadata) ListEvents(string) []string          { return nil }
+func (testMetadata) ListEngines() []string               { return nil }
+func (testMetadata) ListCharsets() []string              { return nil }
+func (testMetadata) ListCollations() []string            { return nil }
+func (testMetadata) ListUsers() []string                 { return nil }
+func (testMetadata) ListTablespaces() []string           { return nil }
+func (testMetadata) ListLogfileGroups() []string         { return nil }
+func (testMetadata) ListSystemVariables() []string       { return nil }
@@ -0,0 +1,151 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompletionItem is a single completion candidate.
+type CompletionItem struct {
+	Kind AutoCompletionImageType
+	// Label is the text shown in the completion list.
+	Label string
+	// InsertText is the text inserted when the item is accepted. It defaults to Label, names of database objects
+	// are quoted where necessary (see QuoteIdentifier).
+	InsertText string
+	// Detail is a short, single line description, e.g. the type of a column or the signature of a routine.
+	Detail string
+	// Documentation is a longer description of the object, e.g. its comment.
+	Documentation string
+	// SortText determines the order of the items, it reflects the order in which they are returned.
+	SortText string
+	// Score is the match quality assigned by FilterItems, higher is better.
+	Score int
+	// Matches holds the positions of the characters in Label matched by FilterItems, for highlighting.
+	Matches []int
+	// RulePath is the chain of parser rules which led to the item, outermost first, e.g. query, simpleStatement,
+	// selectStatement, ..., columnRef. For keywords it ends with the rule containing the keyword.
+	RulePath []string
+
+	// The preferred parser rule for which the item was collected (unused for keywords).
+	rule int
+	// Ranking bonus for columns and aliases belonging to a table reference close to the caret.
+	proximity int
+	// Set for database objects, whose insert text is their (quoted) name.
+	identifier bool
+	// Set for built-in functions, which are ranked below the database objects.
+	builtin bool
+}
+
+// String returns the legacy "kind(label)" representation of the item.
+func (i CompletionItem) String() string {
+	return fmt.Sprintf("%d(%s)", i.Kind, i.Label)
+}
+
+func qualifiedName(schema, name string) string {
+	if len(schema) == 0 {
+		return name
+	}
+	return schema + "." + name
+}
+
+func describeTable(describer MetadataDescriber, schema, name string) (detail, documentation string) {
+	detail = qualifiedName(schema, name)
+	if describer == nil {
+		return detail, ""
+	}
+	table := describer.DescribeTable(schema, name)
+	if table == nil {
+		return detail, ""
+	}
+
+	var lines []string
+	if len(table.Comment) != 0 {
+		lines = append(lines, table.Comment)
+	}
+	if len(table.Engine) != 0 {
+		lines = append(lines, "Engine: "+table.Engine)
+	}
+	return detail, strings.Join(lines, "\n")
+}
+
+func describeView(describer MetadataDescriber, schema, name string) (detail, documentation string) {
+	detail = qualifiedName(schema, name)
+	if describer == nil {
+		return detail, ""
+	}
+	view := describer.DescribeView(schema, name)
+	if view == nil {
+		return detail, ""
+	}
+
+	var lines []string
+	if len(view.Comment) != 0 {
+		lines = append(lines, view.Comment)
+	}
+	if len(view.Definition) != 0 {
+		lines = append(lines, view.Definition)
+	}
+	return detail, strings.Join(lines, "\n")
+}
+
+func describeColumn(describer MetadataDescriber, schema, table, name string) (detail, documentation string) {
+	if describer == nil {
+		return "", ""
+	}
+	column := describer.DescribeColumn(schema, table, name)
+	if column == nil {
+		return "", ""
+	}
+
+	lines := []string{"Column of " + qualifiedName(schema, table)}
+	if column.Nullable {
+		lines = append(lines, "Nullable")
+	} else {
+		lines = append(lines, "Not null")
+	}
+	if column.Default != nil {
+		lines = append(lines, "Default: "+*column.Default)
+	}
+	if len(column.Comment) != 0 {
+		lines = append(lines, column.Comment)
+	}
+	return column.Type, strings.Join(lines, "\n")
+}
+
+func describeRoutine(describer MetadataDescriber, schema, name string, routineType RoutineType) (detail, documentation string) {
+	if describer == nil {
+		return "", ""
+	}
+	routine := describer.DescribeRoutine(schema, name, routineType)
+	if routine == nil {
+		return "", ""
+	}
+	return routine.Signature(), routine.Comment
+}
+
+// Signature returns the routine's parameter list and return type, e.g. "order_count(customer int) RETURNS int".
+func (r *RoutineMetadata) Signature() string {
+	signature := r.Name + "(" + strings.Join(r.parameterLabels(), ", ") + ")"
+	if len(r.Returns) != 0 {
+		signature += " RETURNS " + r.Returns
+	}
+	return signature
+}
+
+// parameterLabels returns the parameters as written in the signature, e.g. "IN customer int".
+func (r *RoutineMetadata) parameterLabels() []string {
+	var result []string
+	for _, parameter := range r.Parameters {
+		var parts []string
+		if len(parameter.Mode) != 0 {
+			parts = append(parts, parameter.Mode)
+		}
+		parts = append(parts, parameter.Name)
+		if len(parameter.Type) != 0 {
+			parts = append(parts, parameter.Type)
+		}
+		result = append(result, strings.Join(parts, " "))
+	}
+	return result
+}
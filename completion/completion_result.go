@@ -0,0 +1,261 @@
+package completion
+
+import (
+	"context"
+	"unicode"
+
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// Position is a location in the query text. Line is one-based, Column is the zero-based index within the line,
+// counted in the completer's CaretEncoding (code points by default) like the caret, and Offset is the zero-based
+// byte offset from the start of the text.
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+// Range is a span of the query text, End is exclusive.
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// CompletionResult holds the completion items together with the part of the text they replace.
+type CompletionResult struct {
+	Items []CompletionItem
+	// Range spans the word at the caret, i.e. the text an accepted item replaces. Start and End are equal if the
+	// caret isn't placed in or directly after a word.
+	Range Range
+	// Prefix is the part of the word which has been typed before the caret.
+	Prefix string
+	// Incomplete is set if the candidate collection was stopped early, because the context was done or the
+	// completer's budget ran out. Items holds what has been found until then.
+	Incomplete bool
+	// Reason tells why there are no items if the caret is placed where nothing can be completed.
+	Reason NoCompletionReason
+}
+
+// NoCompletionReason is the reason for not completing at a caret position.
+type NoCompletionReason int
+
+const (
+	// NoCompletionReasonNone means the caret position can be completed.
+	NoCompletionReasonNone NoCompletionReason = iota
+	// NoCompletionReasonString means the caret is placed within a string literal.
+	NoCompletionReasonString
+	// NoCompletionReasonComment means the caret is placed within a comment (or the version marker of an
+	// executable comment). The content of executable comments like /*!80000 ... */ is completed as usual.
+	NoCompletionReasonComment
+)
+
+// GetCompletionResult works like GetCompletionItems but additionally determines the replacement range and the
+// typed prefix at the caret. It uses the default completer.
+func GetCompletionResult(caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) *CompletionResult {
+	return defaultCompleter.GetCompletionResult(caretLine, caretOffset, defaultSchema, uppercaseKeywords, parser, metadata)
+}
+
+// GetCompletionResult works like GetCompletionItems but additionally determines the replacement range and the
+// typed prefix at the caret.
+func (c *Completer) GetCompletionResult(caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) *CompletionResult {
+	return c.GetCompletionResultContext(context.Background(), caretLine, caretOffset, defaultSchema, uppercaseKeywords, parser, metadata)
+}
+
+// GetCompletionResultContext works like GetCompletionResult, but stops collecting candidates when ctx is done or
+// the completer's budget is exhausted. The result is marked as incomplete then.
+func (c *Completer) GetCompletionResultContext(ctx context.Context, caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) *CompletionResult {
+	result := &CompletionResult{}
+	text := parserText(parser)
+	caret := caretIndex(text, caretLine, caretOffset, c.CaretEncoding)
+	if result.Reason = c.caretReason(parser, caretLine, caretOffset); result.Reason != NoCompletionReasonNone {
+		result.Range.Start = positionOf(text, caret, c.CaretEncoding)
+		result.Range.End = result.Range.Start
+		return result
+	}
+
+	result.Items, result.Incomplete = c.completionItems(ctx, caretLine, caretOffset, defaultSchema, uppercaseKeywords, parser, metadata)
+
+	start, end := wordAt(parser, text, caret)
+	result.Range.Start = positionOf(text, start, c.CaretEncoding)
+	result.Range.End = positionOf(text, end, c.CaretEncoding)
+	result.Prefix = string(text[start:caret])
+	if start < caret && lexerOf(parser).SQLMode.isIdentifierQuote(text[start]) {
+		completeQuote(result.Items, text[start])
+	}
+	return result
+}
+
+// wordAt returns the start and end index of the word at the caret index, or the caret index for both if there is
+// none. A word starting with an unclosed identifier quote includes the quote.
+func wordAt(parser *mysql.MySQLParser, text []rune, caret int) (start, end int) {
+	stream := parser.GetTokenStream().(*antlr.CommonTokenStream)
+	stream.Fill()
+
+	start, end = caret, caret
+	for _, token := range stream.GetAllTokens() {
+		if token.GetTokenType() == antlr.TokenEOF || token.GetChannel() != antlr.TokenDefaultChannel {
+			continue
+		}
+		if token.GetStart() > caret {
+			break
+		}
+		if token.GetStop()+1 >= caret && isWordToken(token) {
+			start, end = token.GetStart(), token.GetStop()+1
+			break
+		}
+	}
+
+	if start == caret {
+		// The lexer drops a quoted identifier which isn't closed yet, find its opening quote directly in the text.
+		start = unterminatedQuoteStart(stream.GetAllTokens(), text, caret, lexerOf(parser).SQLMode)
+	}
+	return start, end
+}
+
+// parserText returns the text the parser's lexer works on.
+func parserText(parser *mysql.MySQLParser) []rune {
+	input := parser.GetTokenStream().GetTokenSource().GetInputStream()
+	return []rune(input.GetText(0, input.Size()-1))
+}
+
+// caretReason determines whether the caret is placed within a string literal or a comment, where nothing can be
+// completed. It is decided by the token at the caret, so quoting follows the lexer and its sql_mode: double quoted
+// text is an identifier with ANSI_QUOTES. The content of executable comments (/*! ... */) is lexed as code and can
+// be completed.
+func (c *Completer) caretReason(parser *mysql.MySQLParser, caretLine int, caretOffset int) NoCompletionReason {
+	text := parserText(parser)
+	caret := caretIndex(text, caretLine, caretOffset, c.CaretEncoding)
+	scanner := NewScanner(parser.GetTokenStream().(*antlr.CommonTokenStream))
+	if !scanner.AdvanceToCaret(caretLine, caretOffset, c.CaretEncoding) {
+		return NoCompletionReasonNone
+	}
+
+	// Go to the last token which starts before the caret.
+	for scanner.Token().GetStart() >= caret {
+		if !scanner.Previous(false /* skipHidden */) {
+			return NoCompletionReasonNone
+		}
+	}
+
+	// The lexer splits a block comment which isn't closed yet into a division and a multiplication operator.
+	scanner.Push()
+	for {
+		if scanner.Is(mysql.MySQLLexerMULT_OPERATOR) && scanner.LookBack(false /* skipHidden */) == mysql.MySQLLexerDIV_OPERATOR {
+			return NoCompletionReasonComment
+		}
+		if !scanner.Previous(false /* skipHidden */) {
+			break
+		}
+	}
+	scanner.Pop()
+
+	token := scanner.Token()
+	switch {
+	case caret <= token.GetStop(): // Within the token.
+		switch token.GetTokenType() {
+		case mysql.MySQLLexerSINGLE_QUOTED_TEXT, mysql.MySQLLexerDOUBLE_QUOTED_TEXT, mysql.MySQLLexerNCHAR_TEXT:
+			return NoCompletionReasonString
+		case mysql.MySQLLexerBLOCK_COMMENT, mysql.MySQLLexerPOUND_COMMENT, mysql.MySQLLexerDASHDASH_COMMENT,
+			mysql.MySQLLexerVERSION_COMMENT_START, mysql.MySQLLexerMYSQL_COMMENT_START:
+			return NoCompletionReasonComment
+		}
+
+	case caret == token.GetStop()+1: // Directly after the token.
+		switch token.GetTokenType() {
+		case mysql.MySQLLexerPOUND_COMMENT, mysql.MySQLLexerDASHDASH_COMMENT:
+			// Line comments end at the line break, which may belong to the comment.
+			if last := text[token.GetStop()]; last != '\n' && last != '\r' {
+				return NoCompletionReasonComment
+			}
+		case mysql.MySQLLexerVERSION_COMMENT_START:
+			return NoCompletionReasonComment
+		}
+
+	default:
+		// The lexer drops a quoted text which isn't closed yet, the caret is placed within it.
+		if r := text[token.GetStop()+1]; (r == '\'' || r == '"') && !lexerOf(parser).SQLMode.isIdentifierQuote(r) {
+			return NoCompletionReasonString
+		}
+	}
+	return NoCompletionReasonNone
+}
+
+// caretIndex converts a caret line and column, counted in the given encoding, into a character index in text.
+func caretIndex(text []rune, caretLine, caretOffset int, encoding CaretEncoding) int {
+	line, column := 1, 0
+	for i, r := range text {
+		if line == caretLine && column >= caretOffset {
+			return i
+		}
+		if r == '\n' {
+			if line == caretLine {
+				return i // Caret behind the end of the line.
+			}
+			line++
+			column = 0
+		} else {
+			column += encoding.width(r)
+		}
+	}
+	return len(text)
+}
+
+// positionOf returns the position of the character index in text, with the column counted in the given encoding.
+func positionOf(text []rune, index int, encoding CaretEncoding) Position {
+	position := Position{Line: 1}
+	for _, r := range text[:index] {
+		position.Offset += len(string(r))
+		if r == '\n' {
+			position.Line++
+			position.Column = 0
+		} else {
+			position.Column += encoding.width(r)
+		}
+	}
+	return position
+}
+
+// isWordToken returns true for tokens which may be completed, i.e. identifiers (quoted or not) and keywords.
+func isWordToken(token antlr.Token) bool {
+	switch token.GetTokenType() {
+	case mysql.MySQLLexerIDENTIFIER, mysql.MySQLLexerBACK_TICK_QUOTED_ID: // Includes double quoted identifiers.
+		return true
+	}
+
+	text := token.GetText()
+	if len(text) == 0 {
+		return false
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || r == '_' || r == '$' || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// unterminatedQuoteStart returns the index of an unclosed identifier quote (a backtick, or a double quote with
+// ANSI_QUOTES) between the last token before the caret and the caret itself. If there is none the caret index is
+// returned.
+func unterminatedQuoteStart(tokens []antlr.Token, text []rune, caret int, mode SQLMode) int {
+	gapStart := 0
+	for _, token := range tokens {
+		if token.GetTokenType() == antlr.TokenEOF || token.GetStart() >= caret {
+			break
+		}
+		gapStart = token.GetStop() + 1
+	}
+	if gapStart >= caret {
+		return caret
+	}
+
+	for i := gapStart; i < caret; i++ {
+		if mode.isIdentifierQuote(text[i]) {
+			return i
+		}
+	}
+	return caret
+}
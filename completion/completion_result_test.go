@@ -0,0 +1,138 @@
+package completion
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionResultRange(t *testing.T) {
+	tests := []struct {
+		input  string
+		prefix string
+		start  Position
+		end    Position
+	}{
+		{input: "SELECT * FROM tab|", prefix: "tab", start: Position{1, 14, 14}, end: Position{1, 17, 17}},
+		{input: "SELECT * FROM ta|ble1", prefix: "ta", start: Position{1, 14, 14}, end: Position{1, 20, 20}},
+		{input: "SELECT * FROM |", prefix: "", start: Position{1, 14, 14}, end: Position{1, 14, 14}},
+		{input: "SELECT * FROM `my ta|", prefix: "`my ta", start: Position{1, 14, 14}, end: Position{1, 20, 20}},
+		{input: "SELECT * FROM t.|", prefix: "", start: Position{1, 16, 16}, end: Position{1, 16, 16}},
+		{input: "SELECT 'äö' FROM t;\nSELECT co|", prefix: "co", start: Position{2, 7, 29}, end: Position{2, 9, 31}},
+	}
+
+	a := require.New(t)
+	for _, test := range tests {
+		parser, caretLine, caretOffset := parseCaret(test.input, 0)
+		result := GetCompletionResult(caretLine, caretOffset, "db", true, parser, testMetadata{})
+
+		a.Equal(test.prefix, result.Prefix, test.input)
+		a.Equal(test.start, result.Range.Start, test.input)
+		a.Equal(test.end, result.Range.End, test.input)
+	}
+}
+
+func TestNoCompletionReason(t *testing.T) {
+	tests := []struct {
+		input  string
+		mode   SQLMode
+		reason NoCompletionReason
+	}{
+		{input: "SELECT 'abc|'", reason: NoCompletionReasonString},
+		{input: "SELECT 'abc|", reason: NoCompletionReasonString},
+		{input: `SELECT 'a\'b|'`, reason: NoCompletionReasonString},
+		{input: "SELECT \"tab|\" FROM table1", reason: NoCompletionReasonString},
+		{input: "SELECT \"tab|\" FROM table1", mode: SQLModeANSIQuotes, reason: NoCompletionReasonNone},
+		{input: "SELECT 'abc' |", reason: NoCompletionReasonNone},
+		{input: "SELECT -- comment |", reason: NoCompletionReasonComment},
+		{input: "SELECT # comment|", reason: NoCompletionReasonComment},
+		{input: "SELECT /* | */ a", reason: NoCompletionReasonComment},
+		{input: "SELECT /* |", reason: NoCompletionReasonComment},
+		{input: "SELECT /* a */ |", reason: NoCompletionReasonNone},
+		{input: "SELECT /*!8000|0 a */", reason: NoCompletionReasonComment},
+		{input: "SELECT /*!80000 | */", reason: NoCompletionReasonNone},
+		{input: "SELECT /*!80000 a */ FROM |", reason: NoCompletionReasonNone},
+		{input: "SELECT N'abc|'", reason: NoCompletionReasonString},
+		{input: `SELECT 'a\' |`, mode: SQLModeNoBackslashEscapes, reason: NoCompletionReasonNone},
+		{input: `SELECT 'a\'b' |`, reason: NoCompletionReasonNone},
+		{input: "SELECT /* it's |", reason: NoCompletionReasonComment},
+		{input: "SELECT /*!80000|", reason: NoCompletionReasonComment},
+	}
+
+	a := require.New(t)
+	for _, test := range tests {
+		parser, caretLine, caretOffset := parseCaret(test.input, test.mode)
+		result := GetCompletionResult(caretLine, caretOffset, "db", true, parser, testMetadata{})
+
+		a.Equal(test.reason, result.Reason, test.input)
+		if test.reason != NoCompletionReasonNone {
+			a.Empty(result.Items, test.input)
+			a.Empty(GetCompletionItems(caretLine, caretOffset, "db", true, parser, testMetadata{}), test.input)
+		} else {
+			a.NotEmpty(result.Items, test.input)
+		}
+	}
+}
+
+const unicodeCatalog = `
+schemas:
+  - name: db
+    tables:
+      - name: 用户
+        columns:
+          - name: 名字
+          - name: 年龄
+      - name: 表😀
+        columns:
+          - name: x😀
+`
+
+func TestCaretEncoding(t *testing.T) {
+	a := require.New(t)
+	catalog, err := NewCatalogFromYAML([]byte(unicodeCatalog))
+	a.NoError(err)
+
+	columns := map[CaretEncoding]func(s string) int{
+		CaretEncodingRune:  func(s string) int { return utf8.RuneCountInString(s) },
+		CaretEncodingUTF16: func(s string) int { return len(utf16.Encode([]rune(s))) },
+		CaretEncodingByte:  func(s string) int { return len(s) },
+	}
+
+	tests := []struct {
+		input  string
+		want   string
+		prefix string
+	}{
+		{input: "SELECT `名字😀`, | FROM 用户", want: "7(年龄)"},
+		{input: "SELECT * FROM `表😀` t WHERE t.|", want: "7(x😀)"},
+		{input: "SELECT * FROM `表😀` t WHERE t.`x|", want: "7(x😀)", prefix: "`x"},
+		{input: "SELECT /* 😀 */ 名字 FROM 用|", want: "3(用户)", prefix: "用"},
+		{input: "SELECT '😀';\nSELECT 年龄 FROM 用户 WHERE 年|", want: "7(年龄)", prefix: "年"},
+	}
+
+	for encoding, column := range columns {
+		completer := NewCompleter()
+		completer.CaretEncoding = encoding
+		for _, test := range tests {
+			index := strings.Index(test.input, "|")
+			script := test.input[:index] + test.input[index+1:]
+			lineStart := strings.LastIndex(test.input[:index], "\n") + 1
+			caretLine := strings.Count(test.input[:index], "\n") + 1
+			caretColumn := column(test.input[lineStart:index])
+
+			result := completer.CompleteScript(script, caretLine, caretColumn, "db", true, catalog)
+			var labels []string
+			for _, item := range result.Items {
+				labels = append(labels, item.String())
+			}
+			a.Contains(labels, test.want, test.input)
+			a.Equal(test.prefix, result.Prefix, test.input)
+			a.Equal(Position{Line: caretLine, Column: caretColumn, Offset: index}, result.Range.End, test.input)
+			a.Equal(caretColumn-column(test.prefix), result.Range.Start.Column, test.input)
+			a.Equal(index-len(test.prefix), result.Range.Start.Offset, test.input)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package completion
+
+import (
+	"unicode"
+)
+
+// MatchMode determines how completion items are matched against the typed prefix.
+type MatchMode int
+
+const (
+	// MatchModePrefix accepts items whose label starts with the prefix, comparing case-sensitively.
+	MatchModePrefix MatchMode = iota
+	// MatchModeCaseInsensitive accepts items whose label starts with the prefix, ignoring case.
+	MatchModeCaseInsensitive
+	// MatchModeFuzzy accepts items whose label contains all characters of the prefix in order (ignoring case),
+	// e.g. "cid" matches "customer_id".
+	MatchModeFuzzy
+)
+
+// Bonuses and penalties used for scoring a match.
+const (
+	scoreMatch       = 1
+	scoreExactCase   = 1
+	scoreConsecutive = 5
+	scoreWordStart   = 8
+	scoreLabelStart  = 10
+	scoreGapPenalty  = 1
+	scoreFullLabel   = 20
+)
+
+// FilterItems returns the items matching prefix in the given mode. The items keep their order, but get a Score
+// (higher is better) and the positions of the matched characters in their label (Matches) assigned.
+// An empty prefix matches everything. A leading quote in the prefix (from a half typed quoted identifier) is ignored.
+func FilterItems(items []CompletionItem, prefix string, mode MatchMode) []CompletionItem {
+	pattern := []rune(prefix)
+	if len(pattern) > 0 && (pattern[0] == '`' || pattern[0] == '"') {
+		pattern = pattern[1:]
+	}
+
+	var result []CompletionItem
+	for _, item := range items {
+		score, matches, ok := match([]rune(item.Label), pattern, mode)
+		if !ok {
+			continue
+		}
+		item.Score = score
+		item.Matches = matches
+		result = append(result, item)
+	}
+	return result
+}
+
+// Filter reduces the items of the result to those matching its prefix.
+func (r *CompletionResult) Filter(mode MatchMode) {
+	r.Items = FilterItems(r.Items, r.Prefix, mode)
+}
+
+func match(label, pattern []rune, mode MatchMode) (score int, matches []int, ok bool) {
+	if len(pattern) == 0 {
+		return 0, nil, true
+	}
+
+	switch mode {
+	case MatchModePrefix, MatchModeCaseInsensitive:
+		if len(pattern) > len(label) {
+			return 0, nil, false
+		}
+		for i, r := range pattern {
+			if label[i] == r {
+				score += scoreExactCase
+			} else if mode == MatchModePrefix || unicode.ToLower(label[i]) != unicode.ToLower(r) {
+				return 0, nil, false
+			}
+			score += scoreMatch
+			matches = append(matches, i)
+		}
+		score += scoreLabelStart
+	case MatchModeFuzzy:
+		previous := -1
+		for _, r := range pattern {
+			position := -1
+			for i := previous + 1; i < len(label); i++ {
+				if unicode.ToLower(label[i]) == unicode.ToLower(r) {
+					position = i
+					break
+				}
+			}
+			if position < 0 {
+				return 0, nil, false
+			}
+
+			score += scoreMatch
+			if label[position] == r {
+				score += scoreExactCase
+			}
+			switch {
+			case position == 0:
+				score += scoreLabelStart
+			case position == previous+1:
+				score += scoreConsecutive
+			case isWordStart(label, position):
+				score += scoreWordStart
+			default:
+				score -= (position - previous - 1) * scoreGapPenalty
+			}
+			matches = append(matches, position)
+			previous = position
+		}
+	default:
+		return 0, nil, false
+	}
+
+	if len(pattern) == len(label) {
+		score += scoreFullLabel
+	}
+	return score, matches, true
+}
+
+// isWordStart returns true if the character at position starts a new word in an identifier like "customer_id"
+// or "customerId".
+func isWordStart(label []rune, position int) bool {
+	previous := label[position-1]
+	if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+		return true
+	}
+	return unicode.IsLower(previous) && unicode.IsUpper(label[position])
+}
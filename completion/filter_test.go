@@ -0,0 +1,52 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterItems(t *testing.T) {
+	a := require.New(t)
+	items := []CompletionItem{
+		{Kind: AutoCompletionImageTypeColumn, Label: "customer_id"},
+		{Kind: AutoCompletionImageTypeColumn, Label: "created_at"},
+		{Kind: AutoCompletionImageTypeTable, Label: "Customers"},
+		{Kind: AutoCompletionImageTypeKeyword, Label: "CID"},
+	}
+
+	labels := func(items []CompletionItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+
+	a.Equal([]string{"customer_id"}, labels(FilterItems(items, "cu", MatchModePrefix)))
+	a.Equal([]string{"customer_id", "Customers"}, labels(FilterItems(items, "cu", MatchModeCaseInsensitive)))
+	a.Equal([]string{"customer_id", "Customers"}, labels(FilterItems(items, "`cu", MatchModeCaseInsensitive)))
+	a.Equal([]string{"customer_id", "CID"}, labels(FilterItems(items, "cid", MatchModeFuzzy)))
+	a.Len(FilterItems(items, "", MatchModePrefix), len(items))
+	a.Empty(FilterItems(items, "xyz", MatchModeFuzzy))
+
+	fuzzy := FilterItems(items, "cid", MatchModeFuzzy)
+	a.Equal([]int{0, 9, 10}, fuzzy[0].Matches)
+	a.Equal([]int{0, 1, 2}, fuzzy[1].Matches)
+	a.Greater(fuzzy[1].Score, fuzzy[0].Score, "a full match ranks higher")
+
+	exact := FilterItems(items, "Cu", MatchModeCaseInsensitive)
+	a.Greater(exact[1].Score, exact[0].Score, "matching case ranks higher")
+}
+
+func TestCompletionResultFilter(t *testing.T) {
+	a := require.New(t)
+	parser, caretLine, caretOffset := parseCaret("SEL|", 0)
+	result := GetCompletionResult(caretLine, caretOffset, "db", true, parser, nil)
+	a.Greater(len(result.Items), 1)
+
+	result.Filter(MatchModeCaseInsensitive)
+	a.Len(result.Items, 1)
+	a.Equal("1(SELECT)", result.Items[0].String())
+	a.Equal([]int{0, 1, 2}, result.Items[0].Matches)
+}
@@ -0,0 +1,85 @@
+package completion
+
+import (
+	"strings"
+	"unicode"
+
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// LetterCase determines the case keywords and function names are offered in.
+type LetterCase int
+
+const (
+	// LetterCaseDefault keeps the default: keywords follow the uppercaseKeywords argument of the completion
+	// functions and function names are lower case, except for those which are keywords in the grammar (like COUNT).
+	// These are cased like keywords.
+	LetterCaseDefault LetterCase = iota
+	// LetterCaseUpper offers upper case words.
+	LetterCaseUpper
+	// LetterCaseLower offers lower case words.
+	LetterCaseLower
+	// LetterCaseMatchPrefix follows the typed prefix: lower case if its first letter is lower case, upper case
+	// otherwise (including when nothing has been typed).
+	LetterCaseMatchPrefix
+)
+
+// FormatOptions determine how the labels of completion items are written.
+type FormatOptions struct {
+	// KeywordCase is the case of keywords.
+	KeywordCase LetterCase
+	// FunctionCase is the case of built-in function names.
+	FunctionCase LetterCase
+	// LowerCaseTableNames is the lower_case_table_names setting of the server. With 1 the names of schemas, tables,
+	// views and triggers and table aliases are offered in lower case, as the server stores and compares them.
+	// Otherwise (0 and 2) all names keep the case they have in the catalog.
+	LowerCaseTableNames int
+}
+
+// functionNameRules are the parser rules in which built-in functions are named by keywords.
+var functionNameRules = map[int]bool{
+	mysql.MySQLParserRULE_sumExpr:             true,
+	mysql.MySQLParserRULE_windowFunctionCall:  true,
+	mysql.MySQLParserRULE_groupingOperation:   true,
+	mysql.MySQLParserRULE_jsonFunction:        true,
+	mysql.MySQLParserRULE_searchJsonFunction:  true,
+	mysql.MySQLParserRULE_runtimeFunctionCall: true,
+}
+
+// format returns word in this case. prefix is the typed prefix, upper the case used by LetterCaseDefault.
+func (c LetterCase) format(word string, prefix string, upper bool) string {
+	switch c {
+	case LetterCaseUpper:
+		upper = true
+	case LetterCaseLower:
+		upper = false
+	case LetterCaseMatchPrefix:
+		upper = true
+		for _, r := range prefix {
+			if unicode.IsLetter(r) {
+				upper = !unicode.IsLower(r)
+				break
+			}
+		}
+	}
+
+	if upper {
+		return strings.ToUpper(word)
+	}
+	return strings.ToLower(word)
+}
+
+// lowerLabels converts the labels of all items to lower case. Items which then have the same label are merged.
+func (m CompletionMap) lowerLabels() {
+	items := m.toSLice()
+	for key := range m {
+		delete(m, key)
+	}
+	for _, item := range items {
+		if item.InsertText == item.Label {
+			item.InsertText = ""
+		}
+		item.Label = strings.ToLower(item.Label)
+		m.Insert(item)
+	}
+}
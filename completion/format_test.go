@@ -0,0 +1,68 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const casingCatalog = `
+schemas:
+  - name: Shop
+    tables:
+      - name: Orders
+        columns:
+          - name: OrderID
+`
+
+func TestFormatOptions(t *testing.T) {
+	a := require.New(t)
+	catalog, err := NewCatalogFromYAML([]byte(casingCatalog))
+	a.NoError(err)
+
+	complete := func(format FormatOptions, uppercaseKeywords bool, input string) []string {
+		completer := NewCompleter()
+		completer.Format = format
+		text, caretOffset := catchCaret(input)
+		var result []string
+		for _, item := range completer.CompleteScript(text, 1, caretOffset, "Shop", uppercaseKeywords, catalog).Items {
+			result = append(result, item.String())
+		}
+		return result
+	}
+
+	// The defaults: keywords as requested, lower case functions and names as in the catalog.
+	items := complete(FormatOptions{}, true, "SELECT |")
+	a.Contains(items, "1(DISTINCT)")
+	a.Contains(items, "1(COUNT)")
+	a.Contains(items, "3(Orders)")
+	items = complete(FormatOptions{}, false, "SELECT |")
+	a.Contains(items, "1(distinct)")
+	a.Contains(items, "1(count)")
+
+	items = complete(FormatOptions{KeywordCase: LetterCaseUpper, FunctionCase: LetterCaseLower}, false, "SELECT |")
+	a.Contains(items, "1(DISTINCT)")
+	a.Contains(items, "1(count)")
+	a.Contains(items, "1(row_number)")
+
+	items = complete(FormatOptions{FunctionCase: LetterCaseUpper}, false, "SELECT |")
+	a.Contains(items, "1(distinct)")
+	a.Contains(items, "1(COUNT)")
+
+	format := FormatOptions{KeywordCase: LetterCaseMatchPrefix, FunctionCase: LetterCaseMatchPrefix}
+	items = complete(format, true, "SELECT di|")
+	a.Contains(items, "1(distinct)")
+	a.Contains(items, "1(count)")
+	items = complete(format, false, "SELECT Di|")
+	a.Contains(items, "1(DISTINCT)")
+	a.Contains(items, "1(COUNT)")
+	a.Contains(complete(format, false, "SELECT |"), "1(DISTINCT)")
+
+	// Only schemas, tables, views and triggers follow lower_case_table_names, columns never do.
+	items = complete(FormatOptions{LowerCaseTableNames: 1}, true, "SELECT | FROM Orders")
+	a.Contains(items, "3(orders)")
+	a.NotContains(items, "3(Orders)")
+	a.Contains(items, "2(shop)")
+	a.Contains(items, "7(OrderID)")
+	a.Contains(complete(FormatOptions{LowerCaseTableNames: 2}, true, "SELECT | FROM Orders"), "3(Orders)")
+}
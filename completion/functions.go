@@ -0,0 +1,85 @@
+package completion
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FunctionMetadata describes a built-in function of the MySQL server.
+type FunctionMetadata struct {
+	Name string `yaml:"name" json:"name"`
+	// Category is one of string, numeric, datetime, flow, information, encryption, miscellaneous, json, spatial,
+	// aggregate and window.
+	Category string `yaml:"category" json:"category"`
+	// Parameters are written as in the MySQL reference manual, e.g. "[len]" for an optional parameter, and a
+	// final "..." repeats the parameter before it.
+	Parameters  []string `yaml:"parameters" json:"parameters"`
+	Returns     string   `yaml:"returns" json:"returns"`
+	Description string   `yaml:"description" json:"description"`
+	// Since is the first server version which has the function, 0 if all supported versions have it.
+	Since int `yaml:"since,omitempty" json:"since,omitempty"`
+}
+
+//go:embed functions.yaml
+var functionsYAML []byte
+
+var (
+	builtinFunctions     = mustLoadFunctions(functionsYAML)
+	builtinFunctionNames = functionIndex(builtinFunctions)
+)
+
+func mustLoadFunctions(data []byte) []*FunctionMetadata {
+	var functions []*FunctionMetadata
+	if err := yaml.Unmarshal(data, &functions); err != nil {
+		panic(fmt.Sprintf("failed to parse the built-in functions: %v", err))
+	}
+	return functions
+}
+
+func functionIndex(functions []*FunctionMetadata) map[string]*FunctionMetadata {
+	result := make(map[string]*FunctionMetadata)
+	for _, function := range functions {
+		result[function.Name] = function
+	}
+	return result
+}
+
+// BuiltinFunctions returns the built-in functions of the given server version (see Completer.ServerVersion),
+// 0 returns all of them. The result must not be modified.
+func BuiltinFunctions(serverVersion int) []*FunctionMetadata {
+	var result []*FunctionMetadata
+	for _, function := range builtinFunctions {
+		if function.availableIn(serverVersion) {
+			result = append(result, function)
+		}
+	}
+	return result
+}
+
+// LookupBuiltinFunction returns the built-in function with the given name (case-insensitive), or nil if there is
+// none. The result must not be modified.
+func LookupBuiltinFunction(name string) *FunctionMetadata {
+	return builtinFunctionNames[strings.ToUpper(name)]
+}
+
+func (f *FunctionMetadata) availableIn(serverVersion int) bool {
+	return serverVersion <= 0 || f.Since <= serverVersion
+}
+
+// Signature returns the function's parameter list and return type, e.g. "SUBSTRING(str, pos, [len]) RETURNS VARCHAR".
+func (f *FunctionMetadata) Signature() string {
+	signature := f.Name + "(" + strings.Join(f.Parameters, ", ") + ")"
+	if len(f.Returns) != 0 {
+		signature += " RETURNS " + f.Returns
+	}
+	return signature
+}
+
+// describeFunction fills the detail and documentation of an item for a built-in function.
+func describeFunction(item *CompletionItem, function *FunctionMetadata) {
+	item.Detail = function.Signature()
+	item.Documentation = function.Description
+}
@@ -0,0 +1,74 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinFunctions(t *testing.T) {
+	a := require.New(t)
+
+	categories := map[string]bool{
+		"string": true, "numeric": true, "datetime": true, "flow": true, "information": true, "encryption": true,
+		"miscellaneous": true, "json": true, "spatial": true, "aggregate": true, "window": true,
+	}
+	names := make(map[string]bool)
+	for _, function := range BuiltinFunctions(0) {
+		a.False(names[function.Name], function.Name)
+		names[function.Name] = true
+		a.True(categories[function.Category], function.Name)
+		a.NotEmpty(function.Returns, function.Name)
+		a.NotEmpty(function.Description, function.Name)
+	}
+
+	substring := LookupBuiltinFunction("substring")
+	a.NotNil(substring)
+	a.Equal("SUBSTRING(str, pos, [len]) RETURNS VARCHAR", substring.Signature())
+	a.Nil(LookupBuiltinFunction("no_such_function"))
+
+	a.Contains(BuiltinFunctions(80032), LookupBuiltinFunction("ROW_NUMBER"))
+	a.NotContains(BuiltinFunctions(ServerVersion57), LookupBuiltinFunction("ROW_NUMBER"))
+	a.Contains(BuiltinFunctions(ServerVersion57), substring)
+	a.NotContains(BuiltinFunctions(ServerVersion56), LookupBuiltinFunction("JSON_EXTRACT"))
+	a.NotContains(BuiltinFunctions(50721), LookupBuiltinFunction("JSON_ARRAYAGG"))
+	a.Contains(BuiltinFunctions(50722), LookupBuiltinFunction("JSON_ARRAYAGG"))
+}
+
+func TestFunctionItems(t *testing.T) {
+	a := require.New(t)
+	complete := func(serverVersion int, input string) map[string]CompletionItem {
+		completer := NewCompleter()
+		completer.ServerVersion = serverVersion
+		text, caretOffset := catchCaret(input)
+		items := make(map[string]CompletionItem)
+		for _, item := range completer.CompleteScript(text, 1, caretOffset, "db", true, testMetadata{}).Items {
+			items[item.String()] = item
+		}
+		return items
+	}
+
+	items := complete(0, "SELECT | FROM table1")
+	a.NotContains(items, "5(runtimeFunction())")
+	a.Equal("CONCAT(str, ...) RETURNS VARCHAR", items["5(concat())"].Detail)
+	a.Equal("Returns the concatenated string, or NULL if any argument is NULL.", items["5(concat())"].Documentation)
+	a.Contains(items, "5(json_extract())")
+	a.Contains(items, "5(st_distance())")
+	a.Contains(items, "5(regexp_like())")
+
+	// Functions which are keywords in the grammar are described, but not offered twice.
+	a.Equal("COUNT([DISTINCT] expr) RETURNS BIGINT", items["1(COUNT)"].Detail)
+	a.NotContains(items, "5(count())")
+	a.NotEmpty(items["1(ROW_NUMBER)"].Documentation)
+
+	items = complete(ServerVersion57, "SELECT | FROM table1")
+	a.Contains(items, "5(concat())")
+	a.NotContains(items, "5(regexp_like())")
+
+	items = complete(ServerVersion56, "SELECT | FROM table1")
+	a.Contains(items, "5(concat())")
+	a.Contains(items, "5(st_distance())")
+	a.NotContains(items, "5(json_extract())")
+	a.NotContains(items, "5(any_value())")
+	a.NotContains(items, "5(st_distance_sphere())")
+}
@@ -0,0 +1,417 @@
+package completion
+
+import (
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// HoverResult describes the object under the cursor.
+type HoverResult struct {
+	// Kind is the type of the object, table aliases are reported as tables.
+	Kind AutoCompletionImageType
+	// Contents is a markdown description of the object, starting with a SQL code block naming it.
+	Contents string
+	// Range spans the hovered word in the script.
+	Range Range
+}
+
+// Hover describes the word under the cursor, using the default completer.
+func Hover(script string, line int, column int, defaultSchema string, metadata MetadataProvider) *HoverResult {
+	return defaultCompleter.Hover(script, line, column, defaultSchema, metadata)
+}
+
+// Hover resolves the word under the cursor to a table, view, column, table alias, schema, function, procedure,
+// system variable or keyword and describes it. The cursor may also be placed directly after the word. Details like
+// column types or table comments are only available if metadata implements MetadataDescriber. Without metadata
+// only keywords and built-in functions are described.
+// The result is nil if there is no word under the cursor or it cannot be resolved.
+func (c *Completer) Hover(script string, line int, column int, defaultSchema string, metadata MetadataProvider) *HoverResult {
+	statement, statementLine, statementColumn, ok := c.statementAt(script, line, column)
+	if !ok {
+		return nil
+	}
+	parser := c.newParser(statement.Text)
+	text := parserText(parser)
+	caret := caretIndex(text, statementLine, statementColumn, c.CaretEncoding)
+	if c.caretReason(parser, statementLine, statementColumn) != NoCompletionReasonNone {
+		return nil
+	}
+
+	scanner := NewScanner(parser.GetTokenStream().(*antlr.CommonTokenStream))
+	if !scanner.AdvanceToCaret(statementLine, statementColumn, c.CaretEncoding) {
+		return nil
+	}
+	if !isWordToken(scanner.Token()) || scanner.TokenChannel() != antlr.TokenDefaultChannel {
+		// A caret directly after a word still belongs to it.
+		if !scanner.Previous(false /* skipHidden */) || !isWordToken(scanner.Token()) || scanner.Token().GetStop()+1 != caret {
+			return nil
+		}
+	}
+
+	resolver := hoverResolver{
+		completer:     c,
+		parser:        parser,
+		scanner:       scanner,
+		lexer:         lexerOf(parser),
+		defaultSchema: defaultSchema,
+		metadata:      metadata,
+	}
+	resolver.describer, _ = metadata.(MetadataDescriber)
+
+	token := scanner.Token()
+	result := resolver.resolve()
+	if result == nil {
+		return nil
+	}
+	result.Range = Range{
+		Start: statement.Start.add(positionOf(text, token.GetStart(), c.CaretEncoding)),
+		End:   statement.Start.add(positionOf(text, token.GetStop()+1, c.CaretEncoding)),
+	}
+	return result
+}
+
+// hoverResolver resolves the word the scanner is positioned on.
+type hoverResolver struct {
+	completer     *Completer
+	parser        *mysql.MySQLParser
+	scanner       *Scanner
+	lexer         *Lexer
+	defaultSchema string
+	metadata      MetadataProvider
+	describer     MetadataDescriber
+
+	// The table references of the statement, collected on demand.
+	references []*TableReference
+	collected  bool
+}
+
+func (r *hoverResolver) resolve() *HoverResult {
+	index := r.scanner.TokenIndex()
+	word := r.scanner.TokenText()
+	tokenType := r.scanner.TokenType()
+
+	// Collect the dotted name the word is part of, e.g. schema.table.column.
+	parts := []string{unquoteIdentifier(word, r.lexer.SQLMode)}
+	position := 0
+	for len(parts) < 3 && r.scanner.Previous(true /* skipHidden */) && r.scanner.Is(mysql.MySQLLexerDOT_SYMBOL) &&
+		r.scanner.Previous(true /* skipHidden */) && isWordToken(r.scanner.Token()) {
+		parts = append([]string{unquoteIdentifier(r.scanner.TokenText(), r.lexer.SQLMode)}, parts...)
+		position++
+	}
+	r.scanner.Seek(index)
+	for len(parts) < 3 && r.scanner.Next(true /* skipHidden */) && r.scanner.Is(mysql.MySQLLexerDOT_SYMBOL) &&
+		r.scanner.Next(true /* skipHidden */) && isWordToken(r.scanner.Token()) {
+		parts = append(parts, unquoteIdentifier(r.scanner.TokenText(), r.lexer.SQLMode))
+		index = r.scanner.TokenIndex()
+	}
+
+	// The tokens around the complete name.
+	r.scanner.Seek(index)
+	nextIsParenthesis := r.scanner.Next(true /* skipHidden */) && r.scanner.Is(mysql.MySQLLexerOPEN_PAR_SYMBOL)
+	r.scanner.Seek(index)
+	for i := 1; i < len(parts); i++ {
+		r.scanner.Previous(true /* skipHidden */)
+		r.scanner.Previous(true /* skipHidden */)
+	}
+	previous := antlr.TokenInvalidType
+	if r.scanner.Previous(true /* skipHidden */) {
+		previous = r.scanner.TokenType()
+	}
+	r.scanner.Seek(index)
+
+	switch previous {
+	case mysql.MySQLLexerAT_AT_SIGN_SYMBOL:
+		return r.systemVariable(parts)
+	case mysql.MySQLLexerAT_SIGN_SYMBOL:
+		return nil // User variables are not known.
+	}
+	if nextIsParenthesis && position == len(parts)-1 {
+		if result := r.routine(parts, previous == mysql.MySQLLexerCALL_SYMBOL); result != nil {
+			return result
+		}
+	}
+	if r.lexer.IsIdentifier(tokenType) || len(parts) > 1 {
+		if result := r.object(parts, position); result != nil {
+			return result
+		}
+	}
+
+	if len(parts) == 1 {
+		return r.keyword(word)
+	}
+	return nil
+}
+
+// systemVariableScopes are the scopes a system variable can be qualified with, e.g. @@GLOBAL.sql_mode.
+var systemVariableScopes = []string{"Global", "Session", "Local", "Persist", "Persist_only"}
+
+func (r *hoverResolver) systemVariable(parts []string) *HoverResult {
+	description := "System variable"
+	switch len(parts) {
+	case 1:
+	case 2:
+		scope, ok := findName(systemVariableScopes, parts[0])
+		if !ok {
+			return nil
+		}
+		description = scope + " system variable"
+	default:
+		return nil
+	}
+
+	if r.metadata == nil {
+		return nil
+	}
+	if _, ok := findName(r.metadata.ListSystemVariables(), parts[len(parts)-1]); !ok {
+		return nil
+	}
+	return &HoverResult{
+		Kind:     AutoCompletionImageTypeSystemVar,
+		Contents: hoverMarkdown("@@"+strings.Join(parts, "."), description),
+	}
+}
+
+// routine describes a called function or procedure. Built-in functions can't be qualified.
+func (r *hoverResolver) routine(parts []string, procedure bool) *HoverResult {
+	name := parts[len(parts)-1]
+	if !procedure && len(parts) == 1 {
+		if function := LookupBuiltinFunction(name); function != nil && function.availableIn(r.completer.ServerVersion) {
+			return &HoverResult{
+				Kind:     AutoCompletionImageTypeFunction,
+				Contents: hoverMarkdown(function.Signature(), "Built-in "+function.Category+" function", function.Description),
+			}
+		}
+	}
+
+	if r.metadata == nil {
+		return nil
+	}
+	schema := r.defaultSchema
+	if len(parts) > 1 {
+		schema = parts[len(parts)-2]
+	}
+	kind, routineType, list := AutoCompletionImageTypeFunction, RoutineTypeFunction, r.metadata.ListFunctions
+	if procedure {
+		kind, routineType, list = AutoCompletionImageTypeRoutine, RoutineTypeProcedure, r.metadata.ListProcedures
+	}
+	name, ok := findName(list(schema), name)
+	if !ok {
+		return nil
+	}
+
+	detail, documentation := describeRoutine(r.describer, schema, name, routineType)
+	if len(detail) == 0 {
+		detail = qualifiedName(schema, name) + "()"
+	}
+	description := "Stored " + strings.ToLower(string(routineType)) + " of " + schema
+	return &HoverResult{
+		Kind:     kind,
+		Contents: hoverMarkdown(detail, description, documentation),
+	}
+}
+
+// object resolves the part at the given position of a (possibly qualified) name.
+func (r *hoverResolver) object(parts []string, position int) *HoverResult {
+	switch len(parts) {
+	case 3:
+		switch position {
+		case 0:
+			return r.schema(parts[0])
+		case 1:
+			return r.table(parts[0], parts[1])
+		default:
+			return r.column(parts[0], parts[1], parts[2])
+		}
+
+	case 2:
+		reference := r.reference(parts[0])
+		if position == 0 {
+			if reference != nil {
+				return r.tableReference(reference, parts[0])
+			}
+			return r.schema(parts[0])
+		}
+		if reference != nil {
+			return r.column(r.schemaOf(reference), reference.Table, parts[1])
+		}
+		return r.table(parts[0], parts[1])
+
+	default:
+		if reference := r.reference(parts[0]); reference != nil {
+			return r.tableReference(reference, parts[0])
+		}
+		for _, reference := range r.tableReferences() {
+			if result := r.column(r.schemaOf(reference), reference.Table, parts[0]); result != nil {
+				return result
+			}
+		}
+		if result := r.table(r.defaultSchema, parts[0]); result != nil {
+			return result
+		}
+		return r.schema(parts[0])
+	}
+}
+
+func (r *hoverResolver) schema(name string) *HoverResult {
+	if r.metadata == nil {
+		return nil
+	}
+	name, ok := findName(r.metadata.ListSchemas(), name)
+	if !ok {
+		return nil
+	}
+	return &HoverResult{
+		Kind:     AutoCompletionImageTypeSchema,
+		Contents: hoverMarkdown("SCHEMA " + name),
+	}
+}
+
+// table describes a table or view.
+func (r *hoverResolver) table(schema, name string) *HoverResult {
+	if r.metadata == nil {
+		return nil
+	}
+	if table, ok := findName(r.metadata.ListTables(schema), name); ok {
+		detail, documentation := describeTable(r.describer, schema, table)
+		return &HoverResult{
+			Kind:     AutoCompletionImageTypeTable,
+			Contents: hoverMarkdown("TABLE "+detail, documentation),
+		}
+	}
+	if view, ok := findName(r.metadata.ListViews(schema), name); ok {
+		detail, documentation := describeView(r.describer, schema, view)
+		return &HoverResult{
+			Kind:     AutoCompletionImageTypeView,
+			Contents: hoverMarkdown("VIEW "+detail, documentation),
+		}
+	}
+	return nil
+}
+
+func (r *hoverResolver) column(schema, table, name string) *HoverResult {
+	if r.metadata == nil {
+		return nil
+	}
+	name, ok := findName(r.metadata.ListColumns(schema, table), name)
+	if !ok {
+		return nil
+	}
+
+	detail, documentation := describeColumn(r.describer, schema, table, name)
+	if len(documentation) == 0 {
+		documentation = "Column of " + qualifiedName(schema, table)
+	}
+	return &HoverResult{
+		Kind:     AutoCompletionImageTypeColumn,
+		Contents: hoverMarkdown(strings.TrimSpace(name+" "+detail), documentation),
+	}
+}
+
+// tableReference describes a table reference of the statement, name is either its alias or its table.
+func (r *hoverResolver) tableReference(reference *TableReference, name string) *HoverResult {
+	schema := r.schemaOf(reference)
+	result := r.table(schema, reference.Table)
+	if len(reference.Alias) == 0 || result == nil {
+		return result
+	}
+
+	object, describe := "table", describeTable
+	if result.Kind == AutoCompletionImageTypeView {
+		object, describe = "view", describeView
+	}
+	_, documentation := describe(r.describer, schema, reference.Table)
+	result.Contents = hoverMarkdown(qualifiedName(schema, reference.Table)+" AS "+name,
+		"Alias of "+object+" "+qualifiedName(schema, reference.Table), documentation)
+	return result
+}
+
+// reference returns the table reference with the given alias, or the one for the given table if it has no alias.
+func (r *hoverResolver) reference(name string) *TableReference {
+	if len(name) == 0 {
+		return nil
+	}
+	for _, reference := range r.tableReferences() {
+		if strings.EqualFold(reference.Alias, name) {
+			return reference
+		}
+	}
+	for _, reference := range r.tableReferences() {
+		if len(reference.Alias) == 0 && strings.EqualFold(reference.Table, name) {
+			return reference
+		}
+	}
+	return nil
+}
+
+// tableReferences collects the table references of the statement, before and after the hovered word.
+func (r *hoverResolver) tableReferences() []*TableReference {
+	if r.collected {
+		return r.references
+	}
+	r.collected = true
+
+	context := AutoCompletionContext{
+		completer:       r.completer,
+		sqlMode:         r.lexer.SQLMode,
+		ReferencesStack: [][]*TableReference{{}},
+	}
+	index := r.scanner.TokenIndex()
+	context.statementStart, context.statementStop = r.scanner.StatementBounds(index)
+	context.CollectLeadingTableReferences(r.parser, r.scanner, index, false /* forTableAlter */)
+	context.TakeReferencesSnapshot()
+	context.CollectRemainingTableReferences(r.parser, r.scanner)
+	context.TakeReferencesSnapshot()
+	r.scanner.Seek(index)
+
+	r.references = context.References
+	return r.references
+}
+
+func (r *hoverResolver) schemaOf(reference *TableReference) string {
+	if len(reference.Schema) != 0 {
+		return reference.Schema
+	}
+	return r.defaultSchema
+}
+
+func (r *hoverResolver) keyword(word string) *HoverResult {
+	keyword := strings.ToUpper(word)
+	if !allKeywords[keyword] || !hasKeyword(r.completer.ServerVersion, keyword) {
+		return nil
+	}
+
+	description := "Keyword"
+	if isReservedWord(r.completer.ServerVersion, keyword) {
+		description = "Reserved keyword"
+	}
+	return &HoverResult{
+		Kind:     AutoCompletionImageTypeKeyword,
+		Contents: hoverMarkdown(keyword, description),
+	}
+}
+
+// findName returns the name of the list which equals name, ignoring case.
+func findName(names []string, name string) (string, bool) {
+	for _, candidate := range names {
+		if strings.EqualFold(candidate, name) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// hoverMarkdown renders a SQL code block, followed by a paragraph for each line of the descriptions.
+func hoverMarkdown(code string, descriptions ...string) string {
+	var builder strings.Builder
+	builder.WriteString("```sql\n" + code + "\n```")
+	for _, description := range descriptions {
+		for _, line := range strings.Split(description, "\n") {
+			if len(line) != 0 {
+				builder.WriteString("\n\n" + line)
+			}
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,121 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHover(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+	hover := func(input string) *HoverResult {
+		text, caretOffset := catchCaret(input)
+		return Hover(text, 1, caretOffset, "shop", catalog)
+	}
+
+	// Columns, qualified by an alias or not.
+	result := hover("SELECT c.em|ail FROM customers c")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeColumn, result.Kind)
+	a.Equal("```sql\nemail varchar(255)\n```\n\nColumn of shop.customers\n\nNullable", result.Contents)
+	a.Equal(Range{Start: Position{Line: 1, Column: 9, Offset: 9}, End: Position{Line: 1, Column: 14, Offset: 14}}, result.Range)
+	result = hover("SELECT total|, id FROM orders")
+	a.NotNil(result)
+	a.Equal("```sql\ntotal decimal(10,2)\n```\n\nColumn of shop.orders\n\nNot null\n\nDefault: 0.00", result.Contents)
+	result = hover("SELECT shop.orders.|id FROM shop.orders")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeColumn, result.Kind)
+
+	// Aliases, tables and views.
+	result = hover("SELECT |c.email FROM customers c")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeTable, result.Kind)
+	a.Equal("```sql\nshop.customers AS c\n```\n\nAlias of table shop.customers\n\nRegistered customers\n\nEngine: InnoDB", result.Contents)
+	result = hover("SELECT * FROM cust|omers c")
+	a.NotNil(result)
+	a.Equal("```sql\nTABLE shop.customers\n```\n\nRegistered customers\n\nEngine: InnoDB", result.Contents)
+	result = hover("SELECT * FROM order_totals|")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeView, result.Kind)
+	result = hover("SELECT * FROM au|dit.log")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeSchema, result.Kind)
+	a.Equal("```sql\nSCHEMA audit\n```", result.Contents)
+
+	// Functions and procedures.
+	result = hover("SELECT CONC|AT(name, email) FROM customers")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeFunction, result.Kind)
+	a.Contains(result.Contents, "```sql\nCONCAT(str, ...) RETURNS VARCHAR\n```\n\nBuilt-in string function")
+	result = hover("SELECT order_count|(id) FROM customers")
+	a.NotNil(result)
+	a.Contains(result.Contents, "Stored function of shop")
+	result = hover("CALL archive_|orders(NOW(), @n)")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeRoutine, result.Kind)
+	a.Contains(result.Contents, "archive_orders(IN before datetime, OUT archived int)")
+
+	// System variables and keywords.
+	result = hover("SELECT @@sql_|mode")
+	a.NotNil(result)
+	a.Equal("```sql\n@@sql_mode\n```\n\nSystem variable", result.Contents)
+	result = hover("SELECT @@GLOBAL.autocommit|")
+	a.NotNil(result)
+	a.Equal("```sql\n@@GLOBAL.autocommit\n```\n\nGlobal system variable", result.Contents)
+	result = hover("SEL|ECT 1")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeKeyword, result.Kind)
+	a.Equal("```sql\nSELECT\n```\n\nReserved keyword", result.Contents)
+
+	a.Nil(hover("SELECT @@FOO.autocommit|"))
+	a.Nil(hover("SELECT @@``.autocommit|"))
+	a.Nil(hover("SELECT @@GLOBAL.missing|"))
+
+	// Odd qualifiers and incomplete names.
+	a.Nil(hover("SELECT ``.em|ail FROM customers"))
+	a.Nil(hover("SELECT `a``b|` FROM customers"))
+	a.Nil(hover("SELECT c.| FROM customers c"))
+	a.Nil(hover("SELECT x.em|ail FROM customers c"))
+	a.Nil(hover("SELECT |x.email FROM customers c"))
+	a.Nil(hover("SELECT missing.customers.|id FROM customers"))
+	result = hover("SELECT c.|email FROM customers c")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeColumn, result.Kind)
+
+	a.Nil(hover("SELECT missing| FROM customers"))
+	a.Nil(hover("SELECT 'na|me' FROM customers"))
+	a.Nil(hover("SELECT 1 | FROM customers"))
+}
+
+func TestHoverWithoutMetadata(t *testing.T) {
+	a := require.New(t)
+	hover := func(input string) *HoverResult {
+		text, caretOffset := catchCaret(input)
+		return Hover(text, 1, caretOffset, "db", nil)
+	}
+
+	a.Nil(hover("SELECT c1| FROM table2"))
+	a.Nil(hover("SELECT * FROM table2|"))
+	a.Nil(hover("SELECT * FROM db.table2|"))
+	a.Nil(hover("SELECT @@sql_mode|"))
+	a.Nil(hover("CALL my_proc|()"))
+	result := hover("SELECT| 1")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeKeyword, result.Kind)
+	result = hover("SELECT ABS|(1)")
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeFunction, result.Kind)
+}
+
+func TestHoverScript(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+
+	result := Hover("SELECT 1;\nSELECT o.total FROM orders o", 2, 10, "shop", catalog)
+	a.NotNil(result)
+	a.Equal(AutoCompletionImageTypeColumn, result.Kind)
+	a.Equal(Position{Line: 2, Column: 9, Offset: 19}, result.Range.Start)
+}
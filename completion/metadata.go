@@ -0,0 +1,33 @@
+package completion
+
+// MetadataProvider supplies the database objects which are offered as completion candidates.
+// Schema scoped lookups receive the schema name as written in the query (or the default schema if the
+// query doesn't qualify the object). Implementations should return nil for objects they don't know about.
+type MetadataProvider interface {
+	ListSchemas() []string
+	ListTables(schema string) []string
+	ListViews(schema string) []string
+	ListColumns(schema, table string) []string
+	ListIndexes(schema, table string) []string
+	ListFunctions(schema string) []string
+	ListProcedures(schema string) []string
+	ListTriggers(schema string) []string
+	ListEvents(schema string) []string
+	ListEngines() []string
+	ListCharsets() []string
+	ListCollations() []string
+	ListUsers() []string
+	ListTablespaces() []string
+	ListLogfileGroups() []string
+	ListSystemVariables() []string
+}
+
+// MetadataDescriber is optionally implemented by a MetadataProvider to supply details about the objects it
+// lists. These are used to fill the detail and documentation of completion items. All methods return nil
+// for unknown objects.
+type MetadataDescriber interface {
+	DescribeTable(schema, table string) *TableMetadata
+	DescribeView(schema, view string) *ViewMetadata
+	DescribeColumn(schema, table, column string) *ColumnMetadata
+	DescribeRoutine(schema, name string, routineType RoutineType) *RoutineMetadata
+}
@@ -1,13 +1,14 @@
 package completion
 
 import (
-	"fmt"
+	"context"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/antlr4-go/antlr/v4"
 	mysql "github.com/bytebase/mysql-parser"
+
+	"github.com/rebelice/mysql-completer/c3"
 )
 
 type TableReference struct {
@@ -17,102 +18,36 @@ type TableReference struct {
 }
 
 type AutoCompletionContext struct {
-	Candidates *CandidatesCollection
+	Candidates *c3.CandidatesCollection
 	// A hierarchical view of all table references in the code, updated by visiting all relevant FROM clauses after
 	// the candidate collection.
 	// Organized as stack to be able to easily remove sets of references when changing nesting level.
 	ReferencesStack [][]*TableReference
 	// A flat list of possible references for easier lookup.
 	References []*TableReference
+
+	// The token range of the statement which contains the caret. Only this statement is considered for completion.
+	statementStart int
+	statementStop  int
+
+	// The completer which provides the configuration, the default completer if nil.
+	completer *Completer
+	// The sql_mode the statement was lexed in.
+	sqlMode SQLMode
 }
 
 func (c *AutoCompletionContext) CollectCandidates(parser *mysql.MySQLParser, scanner *Scanner, caretOffset int, caretLine int) {
-	c3 := NewCodeCompletionCore(parser)
-	c3.IgnoredTokens = map[int]bool{
-		mysql.MySQLParserEOF:                      true,
-		mysql.MySQLLexerEQUAL_OPERATOR:            true,
-		mysql.MySQLLexerASSIGN_OPERATOR:           true,
-		mysql.MySQLLexerNULL_SAFE_EQUAL_OPERATOR:  true,
-		mysql.MySQLLexerGREATER_OR_EQUAL_OPERATOR: true,
-		mysql.MySQLLexerGREATER_THAN_OPERATOR:     true,
-		mysql.MySQLLexerLESS_OR_EQUAL_OPERATOR:    true,
-		mysql.MySQLLexerLESS_THAN_OPERATOR:        true,
-		mysql.MySQLLexerNOT_EQUAL_OPERATOR:        true,
-		mysql.MySQLLexerNOT_EQUAL2_OPERATOR:       true,
-		mysql.MySQLLexerPLUS_OPERATOR:             true,
-		mysql.MySQLLexerMINUS_OPERATOR:            true,
-		mysql.MySQLLexerMULT_OPERATOR:             true,
-		mysql.MySQLLexerDIV_OPERATOR:              true,
-		mysql.MySQLLexerMOD_OPERATOR:              true,
-		mysql.MySQLLexerLOGICAL_NOT_OPERATOR:      true,
-		mysql.MySQLLexerBITWISE_NOT_OPERATOR:      true,
-		mysql.MySQLLexerSHIFT_LEFT_OPERATOR:       true,
-		mysql.MySQLLexerSHIFT_RIGHT_OPERATOR:      true,
-		mysql.MySQLLexerLOGICAL_AND_OPERATOR:      true,
-		mysql.MySQLLexerBITWISE_AND_OPERATOR:      true,
-		mysql.MySQLLexerBITWISE_XOR_OPERATOR:      true,
-		mysql.MySQLLexerLOGICAL_OR_OPERATOR:       true,
-		mysql.MySQLLexerBITWISE_OR_OPERATOR:       true,
-		mysql.MySQLLexerDOT_SYMBOL:                true,
-		mysql.MySQLLexerCOMMA_SYMBOL:              true,
-		mysql.MySQLLexerSEMICOLON_SYMBOL:          true,
-		mysql.MySQLLexerCOLON_SYMBOL:              true,
-		mysql.MySQLLexerOPEN_PAR_SYMBOL:           true,
-		mysql.MySQLLexerCLOSE_PAR_SYMBOL:          true,
-		mysql.MySQLLexerOPEN_CURLY_SYMBOL:         true,
-		mysql.MySQLLexerCLOSE_CURLY_SYMBOL:        true,
-		mysql.MySQLLexerUNDERLINE_SYMBOL:          true,
-		mysql.MySQLLexerAT_SIGN_SYMBOL:            true,
-		mysql.MySQLLexerAT_AT_SIGN_SYMBOL:         true,
-		mysql.MySQLLexerNULL2_SYMBOL:              true,
-		mysql.MySQLLexerPARAM_MARKER:              true,
-		mysql.MySQLLexerCONCAT_PIPES_SYMBOL:       true,
-		mysql.MySQLLexerAT_TEXT_SUFFIX:            true,
-		mysql.MySQLLexerBACK_TICK_QUOTED_ID:       true,
-		mysql.MySQLLexerSINGLE_QUOTED_TEXT:        true,
-		mysql.MySQLLexerDOUBLE_QUOTED_TEXT:        true,
-		mysql.MySQLLexerNCHAR_TEXT:                true,
-		mysql.MySQLLexerUNDERSCORE_CHARSET:        true,
-		mysql.MySQLLexerIDENTIFIER:                true,
-		mysql.MySQLLexerINT_NUMBER:                true,
-		mysql.MySQLLexerLONG_NUMBER:               true,
-		mysql.MySQLLexerULONGLONG_NUMBER:          true,
-		mysql.MySQLLexerDECIMAL_NUMBER:            true,
-		mysql.MySQLLexerBIN_NUMBER:                true,
-		mysql.MySQLLexerHEX_NUMBER:                true,
-	}
-
-	c3.PreferredRules = map[int]bool{
-		mysql.MySQLParserRULE_schemaRef:            true,
-		mysql.MySQLParserRULE_tableRef:             true,
-		mysql.MySQLParserRULE_tableRefWithWildcard: true,
-		mysql.MySQLParserRULE_filterTableRef:       true,
-		mysql.MySQLParserRULE_columnRef:            true,
-		mysql.MySQLParserRULE_columnInternalRef:    true,
-		mysql.MySQLParserRULE_tableWild:            true,
-		mysql.MySQLParserRULE_functionRef:          true,
-		mysql.MySQLParserRULE_functionCall:         true,
-		mysql.MySQLParserRULE_runtimeFunctionCall:  true,
-		mysql.MySQLParserRULE_triggerRef:           true,
-		mysql.MySQLParserRULE_viewRef:              true,
-		mysql.MySQLParserRULE_procedureRef:         true,
-		mysql.MySQLParserRULE_logfileGroupRef:      true,
-		mysql.MySQLParserRULE_tablespaceRef:        true,
-		mysql.MySQLParserRULE_engineRef:            true,
-		mysql.MySQLParserRULE_collationName:        true,
-		mysql.MySQLParserRULE_charsetName:          true,
-		mysql.MySQLParserRULE_eventRef:             true,
-		mysql.MySQLParserRULE_serverRef:            true,
-		mysql.MySQLParserRULE_user:                 true,
-		mysql.MySQLParserRULE_userVariable:         true,
-		mysql.MySQLParserRULE_systemVariable:       true,
-		mysql.MySQLParserRULE_labelRef:             true,
-		mysql.MySQLParserRULE_setSystemVariable:    true,
-		mysql.MySQLParserRULE_parameterName:        true,
-		mysql.MySQLParserRULE_procedureName:        true,
-		mysql.MySQLParserRULE_identifier:           true,
-		mysql.MySQLParserRULE_labelIdentifier:      true,
+	c.CollectCandidatesContext(context.Background(), parser, scanner, caretOffset, caretLine)
+}
+
+// CollectCandidatesContext works like CollectCandidates, but stops the candidate collection early when ctx is done
+// or the completer's budget is exhausted. Candidates.Incomplete is set in that case.
+func (c *AutoCompletionContext) CollectCandidatesContext(ctx context.Context, parser *mysql.MySQLParser, scanner *Scanner, caretOffset int, caretLine int) {
+	completer := c.completer
+	if completer == nil {
+		completer = defaultCompleter
 	}
+	core := completer.newCodeCompletionCore(parser)
 
 	noSeparatorRequiredFor := map[int]bool{
 		mysql.MySQLLexerEQUAL_OPERATOR:            true,
@@ -159,33 +94,105 @@ func (c *AutoCompletionContext) CollectCandidates(parser *mysql.MySQLParser, sca
 		caretIndex--
 	}
 	c.ReferencesStack = append([][]*TableReference{{}}, c.ReferencesStack...)
-	parser.Reset()
-	context := parser.Query()
 
-	c.Candidates = c3.CollectCandidates(caretIndex, context)
+	// Only the statement containing the caret is relevant. Instead of parsing the script up to the caret, start the
+	// candidate collection with a query context positioned at the first token of that statement.
+	c.statementStart, c.statementStop = scanner.StatementBounds(caretIndex)
+	context := mysql.NewQueryContext(parser, nil, -1)
+	context.SetStart(parser.GetTokenStream().Get(c.statementStart))
+
+	c.Candidates = core.CollectCandidatesContext(ctx, caretIndex, context)
 
 	// Post processing some entries.
 	if len(c.Candidates.Tokens[mysql.MySQLLexerNOT2_SYMBOL]) > 0 {
 		// NOT2 is a NOT with special meaning in the operator precedence chain.
 		// For code completion it's the same as NOT
 		c.Candidates.Tokens[mysql.MySQLLexerNOT_SYMBOL] = c.Candidates.Tokens[mysql.MySQLLexerNOT2_SYMBOL]
+		c.Candidates.TokenPaths[mysql.MySQLLexerNOT_SYMBOL] = c.Candidates.TokenPaths[mysql.MySQLLexerNOT2_SYMBOL]
 		delete(c.Candidates.Tokens, mysql.MySQLLexerNOT2_SYMBOL)
+		delete(c.Candidates.TokenPaths, mysql.MySQLLexerNOT2_SYMBOL)
 	}
 
 	// If a column reference is required then we have to continue scanning the query for table references.
-	for ruleName := range c.Candidates.Rules {
-		if ruleName == mysql.MySQLParserRULE_columnRef {
-			c.CollectLeadingTableReferences(parser, scanner, caretIndex, false /* forTableAlter */)
-			c.TakeReferencesSnapshot()
-			c.CollectRemainingTableReferences(parser, scanner)
-			c.TakeReferencesSnapshot()
+	_, columnRef := c.Candidates.Rules[mysql.MySQLParserRULE_columnRef]
+	_, columnInternalRef := c.Candidates.Rules[mysql.MySQLParserRULE_columnInternalRef]
+	_, indexRef := c.Candidates.Rules[mysql.MySQLParserRULE_indexRef]
+	if columnRef {
+		c.CollectLeadingTableReferences(parser, scanner, caretIndex, false /* forTableAlter */)
+		c.TakeReferencesSnapshot()
+		c.CollectRemainingTableReferences(parser, scanner)
+		c.TakeReferencesSnapshot()
+	} else if columnInternalRef || indexRef {
+		// Note:: rule columnInternalRef is not only used for ALTER TABLE, but atm. we only support that here.
+		c.CollectLeadingTableReferences(parser, scanner, caretIndex, true /* forTableAlter */)
+		if indexRef && len(c.ReferencesStack[0]) == 0 {
+			c.CollectDropIndexTableReference(scanner)
+		}
+		c.TakeReferencesSnapshot()
+	}
+}
+
+// CollectDropIndexTableReference is called if an index reference is required outside of ALTER TABLE, that is in
+// DROP INDEX, where the table follows the index name (DROP INDEX idx ON table).
+func (c *AutoCompletionContext) CollectDropIndexTableReference(scanner *Scanner) {
+	scanner.Push()
+
+	found := scanner.Is(mysql.MySQLLexerON_SYMBOL)
+	for !found && scanner.Next(true /* skipHidden */) && scanner.TokenIndex() <= c.statementStop {
+		found = scanner.Is(mysql.MySQLLexerON_SYMBOL)
+	}
+	if found && scanner.Next(true /* skipHidden */) && isWordToken(scanner.Token()) {
+		var reference TableReference
+		reference.Table = unquoteIdentifier(scanner.TokenText(), c.sqlMode)
+		if scanner.Next(true /* skipHidden */) && scanner.Is(mysql.MySQLLexerDOT_SYMBOL) &&
+			scanner.Next(true /* skipHidden */) && isWordToken(scanner.Token()) {
+			reference.Schema = reference.Table
+			reference.Table = unquoteIdentifier(scanner.TokenText(), c.sqlMode)
+		}
+		c.ReferencesStack[0] = append(c.ReferencesStack[0], &reference)
+	}
+
+	scanner.Pop()
+}
+
+// CollectLabels returns the labels of the compound statements which precede the caret, e.g. l1 in "l1: LOOP".
+func (c *AutoCompletionContext) CollectLabels(scanner *Scanner) []string {
+	caretIndex := scanner.TokenIndex()
+	scanner.Push()
+
+	var result []string
+	scanner.Seek(c.statementStart)
+	for scanner.TokenIndex() < caretIndex {
+		if isWordToken(scanner.Token()) && scanner.TokenChannel() == antlr.TokenDefaultChannel {
+			label := unquoteIdentifier(scanner.TokenText(), c.sqlMode)
+			if !scanner.Next(true /* skipHidden */) {
+				break
+			}
+			if scanner.Is(mysql.MySQLLexerCOLON_SYMBOL) && scanner.TokenIndex() < caretIndex {
+				result = append(result, label)
+			}
+			continue
+		}
+		if !scanner.Next(true /* skipHidden */) {
 			break
-		} else if ruleName == mysql.MySQLParserRULE_columnInternalRef {
-			// Note:: rule columnInternalRef is not only used for ALTER TABLE, but atm. we only support that here.
-			c.CollectLeadingTableReferences(parser, scanner, caretIndex, true /* forTableAlter */)
-			c.TakeReferencesSnapshot()
 		}
 	}
+
+	scanner.Pop()
+	return result
+}
+
+// referenceLevel returns the nesting level of the given table reference, 0 being the query which contains the caret.
+// References which are no longer on the stack are considered to be outside of all current levels.
+func (c *AutoCompletionContext) referenceLevel(reference *TableReference) int {
+	for level, references := range c.ReferencesStack {
+		for _, candidate := range references {
+			if candidate == reference {
+				return level
+			}
+		}
+	}
+	return len(c.ReferencesStack)
 }
 
 func (c *AutoCompletionContext) TakeReferencesSnapshot() {
@@ -208,7 +215,7 @@ func (c *AutoCompletionContext) CollectRemainingTableReferences(parser *mysql.My
 	for {
 		found := scanner.TokenType() == mysql.MySQLLexerFROM_SYMBOL
 		for !found {
-			if !scanner.Next(false /* skipHidden */) {
+			if !scanner.Next(false /* skipHidden */) || scanner.TokenIndex() > c.statementStop {
 				break
 			}
 
@@ -232,7 +239,7 @@ func (c *AutoCompletionContext) CollectRemainingTableReferences(parser *mysql.My
 			return // No more FROM clause found.
 		}
 
-		c.ParseTableReferences(scanner.TokenSubText(), parser)
+		c.ParseTableReferences(scanner.TokenSubTextUntil(c.statementStop), parser)
 		if scanner.TokenType() == mysql.MySQLLexerFROM_SYMBOL {
 			scanner.Next(false /* skipHidden */)
 		}
@@ -245,25 +252,25 @@ func (c *AutoCompletionContext) CollectLeadingTableReferences(parser *mysql.MySQ
 	if forTableAlter {
 		// lexer := parser.GetTokenStream().GetTokenSource().(*mysql.MySQLLexer)
 
-		for scanner.Previous(false /* skipHidden */) && scanner.TokenType() != mysql.MySQLLexerALTER_SYMBOL {
-			// Skip all tokens until FROM
+		for scanner.TokenIndex() > c.statementStart && scanner.Previous(false /* skipHidden */) && scanner.TokenType() != mysql.MySQLLexerALTER_SYMBOL {
+			// Skip all tokens until ALTER
 		}
 
 		if scanner.TokenType() == mysql.MySQLLexerALTER_SYMBOL {
 			scanner.SkipTokenSequence([]int{mysql.MySQLLexerALTER_SYMBOL, mysql.MySQLLexerTABLE_SYMBOL})
 
 			var reference TableReference
-			reference.Table = unquote(scanner.TokenText())
-			if scanner.Next(false /* skipHidden */) && scanner.Is(mysql.MySQLLexerDOT_SYMBOL) {
+			reference.Table = unquoteIdentifier(scanner.TokenText(), c.sqlMode)
+			if scanner.Next(true /* skipHidden */) && scanner.Is(mysql.MySQLLexerDOT_SYMBOL) {
 				reference.Schema = reference.Table
-				scanner.Next(false /* skipHidden */)
-				scanner.Next(false /* skipHidden */) // Why twice?
-				reference.Table = unquote(scanner.TokenText())
+				scanner.Next(true /* skipHidden */)
+				reference.Table = unquoteIdentifier(scanner.TokenText(), c.sqlMode)
 			}
 			c.ReferencesStack[0] = append(c.ReferencesStack[0], &reference)
 		}
+		scanner.Pop()
 	} else {
-		scanner.Seek(0)
+		scanner.Seek(c.statementStart)
 
 		level := 0
 		for {
@@ -295,7 +302,7 @@ func (c *AutoCompletionContext) CollectLeadingTableReferences(parser *mysql.MySQ
 				return // No more FROM clause found.
 			}
 
-			c.ParseTableReferences(scanner.TokenSubText(), parser)
+			c.ParseTableReferences(scanner.TokenSubTextUntil(c.statementStop), parser)
 			if scanner.TokenType() == mysql.MySQLLexerFROM_SYMBOL {
 				scanner.Next(false /* skipHidden */)
 			}
@@ -307,7 +314,7 @@ func (c *AutoCompletionContext) ParseTableReferences(fromClause string, parserTe
 	// We use a local parser just for the FROM clause to avoid messing up tokens on the autocompletion
 	// parser (which would affect the processing of the found candidates)
 	input := antlr.NewInputStream(fromClause)
-	lexer := mysql.NewMySQLLexer(input)
+	lexer := NewLexer(input, c.sqlMode)
 	tokens := antlr.NewCommonTokenStream(lexer, 0)
 	parser := mysql.NewMySQLParser(tokens)
 
@@ -339,13 +346,13 @@ func (l *TableRefListener) ExitTableRef(ctx *mysql.TableRefContext) {
 	if !l.fromClauseMode || l.level == 0 {
 		reference := &TableReference{}
 		if ctx.QualifiedIdentifier() != nil {
-			reference.Table = unquote(ctx.QualifiedIdentifier().Identifier().GetText())
+			reference.Table = unquoteIdentifier(ctx.QualifiedIdentifier().Identifier().GetText(), l.context.sqlMode)
 			if ctx.QualifiedIdentifier().DotIdentifier() != nil {
 				reference.Schema = reference.Table
-				reference.Table = unquote(ctx.QualifiedIdentifier().DotIdentifier().Identifier().GetText())
+				reference.Table = unquoteIdentifier(ctx.QualifiedIdentifier().DotIdentifier().Identifier().GetText(), l.context.sqlMode)
 			}
 		} else {
-			reference.Table = unquote(ctx.DotIdentifier().Identifier().GetText())
+			reference.Table = unquoteIdentifier(ctx.DotIdentifier().Identifier().GetText(), l.context.sqlMode)
 		}
 		l.context.ReferencesStack[0] = append(l.context.ReferencesStack[0], reference)
 	}
@@ -360,7 +367,7 @@ func (l *TableRefListener) ExitTableAlias(ctx *mysql.TableAliasContext) {
 		// Appears after a single or derived table.
 		// Since derived tables can be very complex it is not possible here to determine possible columns for
 		// completion, hence we just walk over them and thus have no field where to store the found alias.
-		l.context.ReferencesStack[0][len(l.context.ReferencesStack[0])-1].Alias = unquote(ctx.Identifier().GetText())
+		l.context.ReferencesStack[0][len(l.context.ReferencesStack[0])-1].Alias = unquoteIdentifier(ctx.Identifier().GetText(), l.context.sqlMode)
 	}
 }
 
@@ -393,12 +400,22 @@ func unquote(s string) string {
 		return s
 	}
 
-	if (s[0] == '`' || s[0] == '\'' || s[0] == '"') && s[0] == s[len(s)-1] {
-		return s[1 : len(s)-1]
+	if quote := s[0]; (quote == '`' || quote == '\'' || quote == '"') && quote == s[len(s)-1] {
+		// Quote characters within the text are doubled.
+		return strings.ReplaceAll(s[1:len(s)-1], string([]byte{quote, quote}), string(quote))
 	}
 	return s
 }
 
+// unquoteIdentifier removes the quotes around an identifier. Double quoted text is only an identifier with
+// ANSI_QUOTES, otherwise it is returned as is.
+func unquoteIdentifier(s string, mode SQLMode) string {
+	if len(s) < 2 || !mode.isIdentifierQuote(rune(s[0])) || s[0] != s[len(s)-1] {
+		return s
+	}
+	return s[1 : len(s)-1]
+}
+
 type AutoCompletionImageType int
 
 const (
@@ -422,78 +439,278 @@ const (
 	AutoCompletionImageTypeUser
 	AutoCompletionImageTypeCharset
 	AutoCompletionImageTypeCollation
+	AutoCompletionImageTypeLabel
 )
 
-type AutoCompletionEntry struct {
-	ImageType AutoCompletionImageType
-	Text      string
+type CompletionMap map[string]CompletionItem
+
+func (m CompletionMap) toSLice() []CompletionItem {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result []CompletionItem
+	for _, key := range keys {
+		result = append(result, m[key])
+	}
+	return result
 }
 
-func (e *AutoCompletionEntry) String() string {
-	return fmt.Sprintf("%d(%s)", e.ImageType, e.Text)
+// Insert adds an item to the map. Items are identified by their kind and label, the first one wins.
+func (m CompletionMap) Insert(item CompletionItem) {
+	if len(item.InsertText) == 0 {
+		item.InsertText = item.Label
+	}
+	key := item.String()
+	if _, exists := m[key]; !exists {
+		m[key] = item
+	}
 }
 
-type CompletionMap map[string]bool
+// insertEntries adds an item for each name, collected for the given parser rule. The optional describe function
+// can fill in additional item details.
+func (m CompletionMap) insertEntries(kind AutoCompletionImageType, rule int, names []string, describe func(item *CompletionItem)) {
+	for _, name := range names {
+		item := CompletionItem{
+			Kind:  kind,
+			Label: name,
+			rule:  rule,
+		}
+		if describe != nil {
+			describe(&item)
+		}
+		m.Insert(item)
+	}
+}
 
-func (m CompletionMap) toSLice() []string {
-	var result []string
-	for key := range m {
-		result = append(result, key)
+func (m CompletionMap) insertSchemas(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
 	}
-	sort.Strings(result)
-	return result
+	m.insertEntries(AutoCompletionImageTypeSchema, rule, metadata.ListSchemas(), nil)
 }
 
-func (m CompletionMap) Insert(entry AutoCompletionEntry) {
-	m[entry.String()] = true
+func (m CompletionMap) insertTables(rule int, metadata MetadataProvider, schemas map[string]bool) {
+	if metadata == nil {
+		return
+	}
+	describer, _ := metadata.(MetadataDescriber)
+	for _, schema := range sortedKeys(schemas) {
+		m.insertEntries(AutoCompletionImageTypeTable, rule, metadata.ListTables(schema), func(item *CompletionItem) {
+			item.Detail, item.Documentation = describeTable(describer, schema, item.Label)
+		})
+	}
 }
 
-func (m CompletionMap) insertSchemas() {
-	// TODO: load schemas
-	m.Insert(AutoCompletionEntry{
-		ImageType: AutoCompletionImageTypeSchema,
-		Text:      "db",
-	})
+func (m CompletionMap) insertViews(rule int, metadata MetadataProvider, schemas map[string]bool) {
+	if metadata == nil {
+		return
+	}
+	describer, _ := metadata.(MetadataDescriber)
+	for _, schema := range sortedKeys(schemas) {
+		m.insertEntries(AutoCompletionImageTypeView, rule, metadata.ListViews(schema), func(item *CompletionItem) {
+			item.Detail, item.Documentation = describeView(describer, schema, item.Label)
+		})
+	}
+}
+
+// insertColumns adds the columns of the given tables. The tables map holds the nesting level of the table
+// reference relative to the caret (0 for the innermost query), which is used for ranking.
+func (m CompletionMap) insertColumns(rule int, metadata MetadataProvider, schemas map[string]bool, tables map[string]int) {
+	if metadata == nil {
+		return
+	}
+	describer, _ := metadata.(MetadataDescriber)
+	for _, schema := range sortedKeys(schemas) {
+		for _, table := range tablesByLevel(tables) {
+			level := tables[table]
+			m.insertEntries(AutoCompletionImageTypeColumn, rule, metadata.ListColumns(schema, table), func(item *CompletionItem) {
+				item.Detail, item.Documentation = describeColumn(describer, schema, table, item.Label)
+				item.proximity = proximity(level)
+			})
+		}
+	}
 }
 
-func (m CompletionMap) insertTables() {
-	// TODO: load tables
-	for i := 0; i < 5; i++ {
-		m.Insert(AutoCompletionEntry{
-			ImageType: AutoCompletionImageTypeTable,
-			Text:      fmt.Sprintf("table%d", i),
+// insertIndexes adds the indexes of the given table references.
+func (m CompletionMap) insertIndexes(rule int, metadata MetadataProvider, references []*TableReference, defaultSchema string) {
+	if metadata == nil {
+		return
+	}
+	for _, reference := range references {
+		schema := reference.Schema
+		if len(schema) == 0 {
+			schema = defaultSchema
+		}
+		m.insertEntries(AutoCompletionImageTypeIndex, rule, metadata.ListIndexes(schema, reference.Table), func(item *CompletionItem) {
+			item.Detail = "index of " + qualifiedName(schema, reference.Table)
+		})
+	}
+}
+
+func (m CompletionMap) insertFunctions(rule int, metadata MetadataProvider, schemas map[string]bool) {
+	if metadata == nil {
+		return
+	}
+	describer, _ := metadata.(MetadataDescriber)
+	for _, schema := range sortedKeys(schemas) {
+		m.insertEntries(AutoCompletionImageTypeFunction, rule, metadata.ListFunctions(schema), func(item *CompletionItem) {
+			item.Detail, item.Documentation = describeRoutine(describer, schema, item.Label, RoutineTypeFunction)
 		})
 	}
 }
 
-func (m CompletionMap) insertViews() {
-	// TODO: load views
-	for i := 0; i < 5; i++ {
-		m.Insert(AutoCompletionEntry{
-			ImageType: AutoCompletionImageTypeView,
-			Text:      fmt.Sprintf("view%d", i),
+func (m CompletionMap) insertProcedures(rule int, metadata MetadataProvider, schemas map[string]bool) {
+	if metadata == nil {
+		return
+	}
+	describer, _ := metadata.(MetadataDescriber)
+	for _, schema := range sortedKeys(schemas) {
+		m.insertEntries(AutoCompletionImageTypeRoutine, rule, metadata.ListProcedures(schema), func(item *CompletionItem) {
+			item.Detail, item.Documentation = describeRoutine(describer, schema, item.Label, RoutineTypeProcedure)
 		})
 	}
 }
 
-func (m CompletionMap) insertColumns(tables map[string]bool) {
-	// TODO: load columns
+func (m CompletionMap) insertTriggers(rule int, metadata MetadataProvider, schemas map[string]bool) {
+	if metadata == nil {
+		return
+	}
+	for _, schema := range sortedKeys(schemas) {
+		m.insertEntries(AutoCompletionImageTypeTrigger, rule, metadata.ListTriggers(schema), nil)
+	}
+}
+
+func (m CompletionMap) insertEvents(rule int, metadata MetadataProvider, schemas map[string]bool) {
+	if metadata == nil {
+		return
+	}
+	for _, schema := range sortedKeys(schemas) {
+		m.insertEntries(AutoCompletionImageTypeEvent, rule, metadata.ListEvents(schema), nil)
+	}
+}
+
+func (m CompletionMap) insertEngines(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeEngine, rule, metadata.ListEngines(), nil)
+}
+
+func (m CompletionMap) insertCharsets(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeCharset, rule, metadata.ListCharsets(), nil)
+}
+
+func (m CompletionMap) insertCollations(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeCollation, rule, metadata.ListCollations(), nil)
+}
+
+func (m CompletionMap) insertUsers(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeUser, rule, metadata.ListUsers(), nil)
+}
+
+func (m CompletionMap) insertTablespaces(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeTableSpace, rule, metadata.ListTablespaces(), nil)
+}
+
+func (m CompletionMap) insertLogfileGroups(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeLogFileGroup, rule, metadata.ListLogfileGroups(), nil)
+}
+
+func (m CompletionMap) insertSystemVariables(rule int, metadata MetadataProvider) {
+	if metadata == nil {
+		return
+	}
+	m.insertEntries(AutoCompletionImageTypeSystemVar, rule, metadata.ListSystemVariables(), nil)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// tablesByLevel returns the table names ordered by their nesting level, innermost first, and name.
+func tablesByLevel(tables map[string]int) []string {
+	var result []string
 	for table := range tables {
-		id, err := strconv.Atoi(table[len(table)-1:])
-		if err != nil {
-			panic(err)
-		}
-		for i := 0; i < id; i++ {
-			m.Insert(AutoCompletionEntry{
-				ImageType: AutoCompletionImageTypeColumn,
-				Text:      fmt.Sprintf("c%d", i),
-			})
+		result = append(result, table)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if tables[result[i]] != tables[result[j]] {
+			return tables[result[i]] < tables[result[j]]
 		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// qualifiedSchemas returns the schemas to look up objects in, for a (possibly empty) schema qualifier.
+func qualifiedSchemas(qualifier string, defaultSchema string) map[string]bool {
+	schemas := make(map[string]bool)
+	if len(qualifier) == 0 {
+		schemas[defaultSchema] = true
+	} else {
+		schemas[qualifier] = true
+	}
+	return schemas
+}
+
+// GetCodeCompletionList returns the completion candidates at the given caret position in their legacy
+// "kind(label)" form. See GetCompletionItems for the structured variant.
+func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) []string {
+	var result []string
+	for _, item := range GetCompletionItems(caretLine, caretOffset, defaultSchema, uppercaseKeywords, parser, metadata) {
+		result = append(result, item.String())
 	}
+	return result
+}
+
+// GetCompletionItems returns the completion candidates at the given caret position, using the default completer.
+func GetCompletionItems(caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) []CompletionItem {
+	return defaultCompleter.GetCompletionItems(caretLine, caretOffset, defaultSchema, uppercaseKeywords, parser, metadata)
+}
+
+// GetCompletionItems returns the completion candidates at the given caret position. Database objects
+// (schemas, tables, columns etc.) are taken from metadata, which may be nil if there is no catalog available.
+// If metadata also implements MetadataDescriber the items carry details and documentation for these objects.
+// The statement is lexed in the sql_mode of the parser's lexer, not in the completer's SQLMode.
+func (c *Completer) GetCompletionItems(caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) []CompletionItem {
+	items, _ := c.completionItems(context.Background(), caretLine, caretOffset, defaultSchema, uppercaseKeywords, parser, metadata)
+	return items
 }
 
-func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser) []string {
-	context := AutoCompletionContext{}
+// completionItems determines the completion items and whether the candidate collection was incomplete.
+func (c *Completer) completionItems(ctx context.Context, caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, parser *mysql.MySQLParser, metadata MetadataProvider) ([]CompletionItem, bool) {
+	text := parserText(parser)
+	caret := caretIndex(text, caretLine, caretOffset, c.CaretEncoding)
+	if c.caretReason(parser, caretLine, caretOffset) != NoCompletionReasonNone {
+		return nil, false
+	}
+
+	context := AutoCompletionContext{
+		completer: c,
+	}
 
 	// A set for each object type. This will sort the groups alphabetically and avoids duplicates,
 	// but allows to add them as groups to the final list.
@@ -515,66 +732,41 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 	charsetEntries := make(CompletionMap)
 	eventEntries := make(CompletionMap)
 
-	// Handled but needs meat yet.
-	// userVarEntries := make(CompletionMap)
-
-	// To be done yet.
 	userEntries := make(CompletionMap)
 	indexEntries := make(CompletionMap)
-	pluginEntries := make(CompletionMap)
-	// fkEntries := make(CompletionMap)
 	labelEntries := make(CompletionMap)
 
-	synonyms := map[int][]string{
-		mysql.MySQLLexerCHAR_SYMBOL:         {"CHARACTER"},
-		mysql.MySQLLexerNOW_SYMBOL:          {"CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP"},
-		mysql.MySQLLexerDAY_SYMBOL:          {"DAYOFMONTH", "SQL_TSI_DAY"},
-		mysql.MySQLLexerDECIMAL_SYMBOL:      {"DEC"},
-		mysql.MySQLLexerDISTINCT_SYMBOL:     {"DISTINCTROW"},
-		mysql.MySQLLexerCOLUMNS_SYMBOL:      {"FIELDS"},
-		mysql.MySQLLexerFLOAT_SYMBOL:        {"FLOAT4"},
-		mysql.MySQLLexerDOUBLE_SYMBOL:       {"FLOAT8"},
-		mysql.MySQLLexerINT_SYMBOL:          {"INTEGER", "INT4"},
-		mysql.MySQLLexerRELAY_THREAD_SYMBOL: {"IO_THREAD"},
-		mysql.MySQLLexerSUBSTRING_SYMBOL:    {"MID", "SUBSTR"},
-		mysql.MySQLLexerMID_SYMBOL:          {"MEDIUMINT"},
-		mysql.MySQLLexerMEDIUMINT_SYMBOL:    {"MIDDLEINT", "INT3"},
-		mysql.MySQLLexerNDBCLUSTER_SYMBOL:   {"NDB"},
-		mysql.MySQLLexerREGEXP_SYMBOL:       {"RLIKE"},
-		mysql.MySQLLexerDATABASE_SYMBOL:     {"SCHEMA"},
-		mysql.MySQLLexerDATABASES_SYMBOL:    {"SCHEMAS"},
-		mysql.MySQLLexerUSER_SYMBOL:         {"SESSION_USER"},
-		mysql.MySQLLexerSTD_SYMBOL:          {"STDDEV", "STDDEV"},
-		mysql.MySQLLexerVARCHAR_SYMBOL:      {"VARCHARACTER"},
-		mysql.MySQLLexerVARIANCE_SYMBOL:     {"VAR_POP"},
-		mysql.MySQLLexerTINYINT_SYMBOL:      {"INT1"},
-		mysql.MySQLLexerSMALLINT_SYMBOL:     {"INT2"},
-		mysql.MySQLLexerBIGINT_SYMBOL:       {"INT8"},
-		mysql.MySQLLexerSECOND_SYMBOL:       {"SQL_TSI_SECOND"},
-		mysql.MySQLLexerMINUTE_SYMBOL:       {"SQL_TSI_MINUTE"},
-		mysql.MySQLLexerHOUR_SYMBOL:         {"SQL_TSI_HOUR"},
-		mysql.MySQLLexerWEEK_SYMBOL:         {"SQL_TSI_WEEK"},
-		mysql.MySQLLexerMONTH_SYMBOL:        {"SQL_TSI_MONTH"},
-		mysql.MySQLLexerQUARTER_SYMBOL:      {"SQL_TSI_QUARTER"},
-		mysql.MySQLLexerYEAR_SYMBOL:         {"SQL_TSI_YEAR"},
-	}
-
 	scanner := NewScanner(parser.GetTokenStream().(*antlr.CommonTokenStream))
-	lexer := parser.GetTokenStream().GetTokenSource().(*mysql.MySQLLexer)
+	lexer := lexerOf(parser)
+	context.sqlMode = lexer.SQLMode
 
 	// Move to caret position and store that on the scanner stack.
-	scanner.AdvanceToPosition(caretLine, caretOffset)
+	scanner.AdvanceToCaret(caretLine, caretOffset, c.CaretEncoding)
 	scanner.Push()
 
-	context.CollectCandidates(parser, scanner, caretOffset, caretLine)
+	context.CollectCandidatesContext(ctx, parser, scanner, caretOffset, caretLine)
+
+	// Keywords and functions may be cased like the word typed so far.
+	start, _ := wordAt(parser, text, caret)
+	prefix := string(text[start:caret])
+
+	// Built-in functions which are offered as keywords, they are not repeated for runtimeFunctionCall.
+	functionKeywords := make(map[string]bool)
 
 	for token, value := range context.Candidates.Tokens {
+		path := rulePath(parser, context.Candidates.TokenPaths[token])
 		entry := parser.SymbolicNames[token]
 		if strings.HasSuffix(entry, "_SYMBOL") {
 			entry = entry[:len(entry)-7]
 		} else {
 			entry = unquote(entry)
 		}
+		function := LookupBuiltinFunction(entry)
+
+		// The grammar is not version aware, so leave out what the server doesn't know.
+		if !hasKeyword(c.ServerVersion, entry) {
+			continue
+		}
 
 		list := 0
 		if len(value) > 0 {
@@ -596,90 +788,195 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 
 		switch list {
 		case 1:
-			runtimeFunctionEntries.Insert(AutoCompletionEntry{
-				ImageType: AutoCompletionImageTypeFunction,
-				Text:      strings.ToLower(entry) + "()",
-			})
+			item := CompletionItem{
+				Kind:     AutoCompletionImageTypeFunction,
+				Label:    c.Format.FunctionCase.format(entry, prefix, false) + "()",
+				RulePath: path,
+				builtin:  true,
+			}
+			if function != nil {
+				describeFunction(&item, function)
+				functionKeywords[function.Name] = true
+			}
+			runtimeFunctionEntries.Insert(item)
 		default:
-			if !uppercaseKeywords {
-				entry = strings.ToLower(entry)
+			// Built-in functions like COUNT or ROW_NUMBER are keywords in the grammar.
+			tokenPath := context.Candidates.TokenPaths[token]
+			if len(tokenPath) == 0 || !functionNameRules[tokenPath[len(tokenPath)-1]] {
+				function = nil
 			}
 
-			keywordEntries.Insert(AutoCompletionEntry{
-				ImageType: AutoCompletionImageTypeKeyword,
-				Text:      entry,
-			})
+			letterCase := c.Format.KeywordCase
+			if function != nil && c.Format.FunctionCase != LetterCaseDefault {
+				letterCase = c.Format.FunctionCase
+			}
+			entry = letterCase.format(entry, prefix, uppercaseKeywords)
+
+			item := CompletionItem{
+				Kind:     AutoCompletionImageTypeKeyword,
+				Label:    entry,
+				RulePath: path,
+			}
+			if function != nil {
+				describeFunction(&item, function)
+				functionKeywords[function.Name] = true
+			}
+			keywordEntries.Insert(item)
 
 			// Add also synonyms, if there are any.
-			if synonyms[token] != nil {
-				for _, synonym := range synonyms[token] {
-					if !uppercaseKeywords {
-						synonym = strings.ToLower(synonym)
+			if c.Synonyms[token] != nil {
+				for _, synonym := range c.Synonyms[token] {
+					if !hasKeyword(c.ServerVersion, synonym) {
+						continue
 					}
+					synonym = letterCase.format(synonym, prefix, uppercaseKeywords)
 
-					keywordEntries.Insert(AutoCompletionEntry{
-						ImageType: AutoCompletionImageTypeKeyword,
-						Text:      synonym,
+					keywordEntries.Insert(CompletionItem{
+						Kind:     AutoCompletionImageTypeKeyword,
+						Label:    synonym,
+						RulePath: path,
 					})
 				}
 			}
 		}
 	}
 
+	// Handle the rules in a fixed order, so that items found for several rules are attributed consistently.
+	var candidates []int
 	for candidate := range context.Candidates.Rules {
+		candidates = append(candidates, candidate)
+	}
+	sort.Ints(candidates)
+
+	for _, candidate := range candidates {
 		// Restore the scanner position to the caret position and store that value again for the next round.
 		scanner.Pop()
 		scanner.Push()
 
 		switch candidate {
 		case mysql.MySQLParserRULE_runtimeFunctionCall:
-			// TODO: load runtime functions
-			runtimeFunctionEntries.Insert(AutoCompletionEntry{
-				ImageType: AutoCompletionImageTypeFunction,
-				Text:      "runtimeFunction()",
-			})
+			for _, function := range BuiltinFunctions(c.ServerVersion) {
+				if functionKeywords[function.Name] {
+					continue
+				}
+				item := CompletionItem{
+					Kind:    AutoCompletionImageTypeFunction,
+					Label:   c.Format.FunctionCase.format(function.Name, prefix, false) + "()",
+					rule:    candidate,
+					builtin: true,
+				}
+				describeFunction(&item, function)
+				runtimeFunctionEntries.Insert(item)
+			}
 		case mysql.MySQLParserRULE_schemaRef:
-			schemaEntries.insertSchemas()
+			schemaEntries.insertSchemas(candidate, metadata)
 		case mysql.MySQLParserRULE_tableRefWithWildcard:
 			// A special form of table references (id.id.*) used only in multi-table delete.
 			// Handling is similar as for column references (just that we have table/view objects instead of column refs).
 			schema, _, flags := determineSchemaTableQualifier(scanner, lexer)
 			if flags&ObjectFlagsShowSchemas != 0 {
-				schemaEntries.insertSchemas()
+				schemaEntries.insertSchemas(candidate, metadata)
 			}
 
-			schemas := make(map[string]bool)
-			if len(schema) == 0 {
-				schemas[defaultSchema] = true
-			} else {
-				schemas[schema] = true
-			}
+			schemas := qualifiedSchemas(schema, defaultSchema)
 			if flags&ObjectFlagsShowTables != 0 {
-				tableEntries.insertTables()
-				viewEntries.insertViews()
+				tableEntries.insertTables(candidate, metadata, schemas)
+				viewEntries.insertViews(candidate, metadata, schemas)
 			}
 		case mysql.MySQLParserRULE_tableRef, mysql.MySQLParserRULE_filterTableRef:
-			qualifier, flags := determineQualifier(scanner, lexer, caretOffset)
+			qualifier, flags := determineQualifier(scanner, lexer)
 
 			if flags&ObjectFlagsShowFirst != 0 {
-				schemaEntries.insertSchemas()
+				schemaEntries.insertSchemas(candidate, metadata)
 			}
 
 			if flags&ObjectFlagsShowSecond != 0 {
-				schemas := make(map[string]bool)
-				if len(qualifier) == 0 {
-					schemas[defaultSchema] = true
-				} else {
-					schemas[qualifier] = true
-				}
+				schemas := qualifiedSchemas(qualifier, defaultSchema)
+				tableEntries.insertTables(candidate, metadata, schemas)
+				viewEntries.insertViews(candidate, metadata, schemas)
+			}
+		case mysql.MySQLParserRULE_viewRef:
+			qualifier, flags := determineQualifier(scanner, lexer)
+
+			if flags&ObjectFlagsShowFirst != 0 {
+				schemaEntries.insertSchemas(candidate, metadata)
+			}
+
+			if flags&ObjectFlagsShowSecond != 0 {
+				viewEntries.insertViews(candidate, metadata, qualifiedSchemas(qualifier, defaultSchema))
+			}
+		case mysql.MySQLParserRULE_functionRef, mysql.MySQLParserRULE_functionCall:
+			qualifier, flags := determineQualifier(scanner, lexer)
+
+			if flags&ObjectFlagsShowFirst != 0 {
+				schemaEntries.insertSchemas(candidate, metadata)
+			}
+
+			if flags&ObjectFlagsShowSecond != 0 {
+				functionEntries.insertFunctions(candidate, metadata, qualifiedSchemas(qualifier, defaultSchema))
+			}
+		case mysql.MySQLParserRULE_procedureRef:
+			qualifier, flags := determineQualifier(scanner, lexer)
+
+			if flags&ObjectFlagsShowFirst != 0 {
+				schemaEntries.insertSchemas(candidate, metadata)
+			}
+
+			if flags&ObjectFlagsShowSecond != 0 {
+				procedureEntries.insertProcedures(candidate, metadata, qualifiedSchemas(qualifier, defaultSchema))
+			}
+		case mysql.MySQLParserRULE_triggerRef:
+			qualifier, flags := determineQualifier(scanner, lexer)
+
+			if flags&ObjectFlagsShowFirst != 0 {
+				schemaEntries.insertSchemas(candidate, metadata)
+			}
 
-				tableEntries.insertTables()
-				viewEntries.insertViews()
+			if flags&ObjectFlagsShowSecond != 0 {
+				triggerEntries.insertTriggers(candidate, metadata, qualifiedSchemas(qualifier, defaultSchema))
+			}
+		case mysql.MySQLParserRULE_eventRef:
+			qualifier, flags := determineQualifier(scanner, lexer)
+
+			if flags&ObjectFlagsShowFirst != 0 {
+				schemaEntries.insertSchemas(candidate, metadata)
+			}
+
+			if flags&ObjectFlagsShowSecond != 0 {
+				eventEntries.insertEvents(candidate, metadata, qualifiedSchemas(qualifier, defaultSchema))
+			}
+		case mysql.MySQLParserRULE_engineRef:
+			engineEntries.insertEngines(candidate, metadata)
+		case mysql.MySQLParserRULE_charsetName:
+			charsetEntries.insertCharsets(candidate, metadata)
+		case mysql.MySQLParserRULE_collationName:
+			collationEntries.insertCollations(candidate, metadata)
+		case mysql.MySQLParserRULE_user:
+			userEntries.insertUsers(candidate, metadata)
+		case mysql.MySQLParserRULE_tablespaceRef:
+			tableSpaceEntries.insertTablespaces(candidate, metadata)
+		case mysql.MySQLParserRULE_logfileGroupRef:
+			logFileGroupEntries.insertLogfileGroups(candidate, metadata)
+		case mysql.MySQLParserRULE_systemVariable, mysql.MySQLParserRULE_setSystemVariable:
+			systemVarEntries.insertSystemVariables(candidate, metadata)
+		case mysql.MySQLParserRULE_indexRef:
+			// The index of the table being altered or dropped from.
+			indexEntries.insertIndexes(candidate, metadata, context.References, defaultSchema)
+		case mysql.MySQLParserRULE_columnInternalRef:
+			// A column of the table being altered.
+			for _, reference := range context.References {
+				schemas := qualifiedSchemas(reference.Schema, defaultSchema)
+				columnEntries.insertColumns(candidate, metadata, schemas, map[string]int{reference.Table: 0})
 			}
+		case mysql.MySQLParserRULE_labelRef:
+			labelEntries.insertEntries(AutoCompletionImageTypeLabel, candidate, context.CollectLabels(scanner), nil)
+		case mysql.MySQLParserRULE_serverRef, mysql.MySQLParserRULE_userVariable, mysql.MySQLParserRULE_parameterName:
+			// Servers and user variables are not part of the metadata and parameter names are new names,
+			// these rules are only preferred to keep their identifier keywords out of the candidates.
 		case mysql.MySQLParserRULE_tableWild, mysql.MySQLParserRULE_columnRef:
 			schema, table, flags := determineSchemaTableQualifier(scanner, lexer)
 			if flags&ObjectFlagsShowSchemas != 0 {
-				schemaEntries.insertSchemas()
+				schemaEntries.insertSchemas(candidate, metadata)
 			}
 
 			schemas := make(map[string]bool)
@@ -698,21 +995,26 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 			}
 
 			if flags&ObjectFlagsShowTables != 0 {
-				tableEntries.insertTables()
+				tableEntries.insertTables(candidate, metadata, schemas)
 				if candidate == mysql.MySQLParserRULE_columnRef {
-					viewEntries.insertViews()
+					viewEntries.insertViews(candidate, metadata, schemas)
 
 					for _, reference := range context.References {
 						if (len(schema) == 0 && len(reference.Schema) == 0) || schemas[reference.Schema] {
 							if len(reference.Alias) == 0 {
-								tableEntries.Insert(AutoCompletionEntry{
-									ImageType: AutoCompletionImageTypeTable,
-									Text:      reference.Table,
+								tableEntries.Insert(CompletionItem{
+									Kind:      AutoCompletionImageTypeTable,
+									Label:     reference.Table,
+									rule:      candidate,
+									proximity: proximity(context.referenceLevel(reference)),
 								})
 							} else {
-								tableEntries.Insert(AutoCompletionEntry{
-									ImageType: AutoCompletionImageTypeTable,
-									Text:      reference.Alias,
+								tableEntries.Insert(CompletionItem{
+									Kind:      AutoCompletionImageTypeTable,
+									Label:     reference.Alias,
+									Detail:    "alias for " + qualifiedName(reference.Schema, reference.Table),
+									rule:      candidate,
+									proximity: proximity(context.referenceLevel(reference)),
 								})
 							}
 						}
@@ -725,25 +1027,31 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 					schemas[defaultSchema] = true
 				}
 
-				tables := make(map[string]bool)
+				// Tables with their nesting level relative to the caret.
+				tables := make(map[string]int)
 				if len(table) != 0 {
-					tables[table] = true
+					tables[table] = 0
 
 					// Could be an alias
 					for _, reference := range context.References {
 						if strings.EqualFold(reference.Alias, table) {
-							tables[reference.Table] = true
-							schemas[reference.Schema] = true
+							tables[reference.Table] = 0
+							if len(reference.Schema) != 0 {
+								schemas[reference.Schema] = true
+							}
 						}
 					}
 				} else if len(context.References) > 0 && candidate == mysql.MySQLParserRULE_columnRef {
 					for _, reference := range context.References {
-						tables[reference.Table] = true
+						level := context.referenceLevel(reference)
+						if current, exists := tables[reference.Table]; !exists || level < current {
+							tables[reference.Table] = level
+						}
 					}
 				}
 
 				if len(tables) > 0 {
-					columnEntries.insertColumns(tables)
+					columnEntries.insertColumns(candidate, metadata, schemas, tables)
 				}
 			}
 
@@ -752,7 +1060,20 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 	}
 
 	scanner.Pop() // Clear the scanner stack.
-	var result []string
+
+	if c.Format.LowerCaseTableNames == 1 {
+		for _, entries := range []CompletionMap{schemaEntries, tableEntries, viewEntries, triggerEntries} {
+			entries.lowerLabels()
+		}
+	}
+
+	for _, entries := range []CompletionMap{columnEntries, labelEntries, tableEntries, viewEntries, schemaEntries,
+		functionEntries, procedureEntries, triggerEntries, indexEntries, eventEntries, logFileGroupEntries,
+		tableSpaceEntries} {
+		entries.quoteIdentifiers(lexer.SQLMode, c.ServerVersion)
+	}
+
+	var result []CompletionItem
 	result = append(result, keywordEntries.toSLice()...)
 	result = append(result, columnEntries.toSLice()...)
 	result = append(result, userEntries.toSLice()...)
@@ -765,9 +1086,7 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 	result = append(result, triggerEntries.toSLice()...)
 	result = append(result, indexEntries.toSLice()...)
 	result = append(result, eventEntries.toSLice()...)
-	result = append(result, userEntries.toSLice()...)
 	result = append(result, engineEntries.toSLice()...)
-	result = append(result, pluginEntries.toSLice()...)
 	result = append(result, logFileGroupEntries.toSLice()...)
 	result = append(result, tableSpaceEntries.toSLice()...)
 	result = append(result, charsetEntries.toSLice()...)
@@ -775,6 +1094,22 @@ func GetCodeCompletionList(caretLine int, caretOffset int, defaultSchema string,
 	result = append(result, runtimeFunctionEntries.toSLice()...)
 	result = append(result, systemVarEntries.toSLice()...)
 
+	for i := range result {
+		if result[i].RulePath == nil {
+			if path, exists := context.Candidates.Rules[result[i].rule]; exists {
+				result[i].RulePath = rulePath(parser, path, result[i].rule)
+			}
+		}
+	}
+	return RankItems(result, c.Usage), context.Candidates.Incomplete
+}
+
+// rulePath returns the names of the rules in path, followed by the names of the given rules.
+func rulePath(parser *mysql.MySQLParser, path []int, rules ...int) []string {
+	result := make([]string, 0, len(path)+len(rules))
+	for _, rule := range append(path[:len(path):len(path)], rules...) {
+		result = append(result, parser.RuleNames[rule])
+	}
 	return result
 }
 
@@ -788,7 +1123,7 @@ const (
 	ObjectFlagsShowSecond
 )
 
-func determineSchemaTableQualifier(scanner *Scanner, lexer *mysql.MySQLLexer) (schema, table string, flags ObjectFlags) {
+func determineSchemaTableQualifier(scanner *Scanner, lexer *Lexer) (schema, table string, flags ObjectFlags) {
 	position := scanner.TokenIndex()
 	if scanner.TokenChannel() != 0 {
 		scanner.Next(true /* skipHidden */) // First skip to the next non-hidden token.
@@ -820,7 +1155,7 @@ func determineSchemaTableQualifier(scanner *Scanner, lexer *mysql.MySQLLexer) (s
 	table = ""
 	temp := ""
 	if lexer.IsIdentifier(scanner.TokenType()) {
-		temp = unquote(scanner.TokenText())
+		temp = unquoteIdentifier(scanner.TokenText(), lexer.SQLMode)
 		scanner.Next(true /* skipHidden */)
 	}
 
@@ -832,7 +1167,7 @@ func determineSchemaTableQualifier(scanner *Scanner, lexer *mysql.MySQLLexer) (s
 	table = temp
 	schema = temp
 	if lexer.IsIdentifier(scanner.TokenType()) {
-		temp = unquote(scanner.TokenText())
+		temp = unquoteIdentifier(scanner.TokenText(), lexer.SQLMode)
 		scanner.Next(true /* skipHidden */)
 
 		if !scanner.Is(mysql.MySQLLexerDOT_SYMBOL) || position <= scanner.TokenIndex() {
@@ -846,7 +1181,7 @@ func determineSchemaTableQualifier(scanner *Scanner, lexer *mysql.MySQLLexer) (s
 	return schema, table, ObjectFlagsShowTables | ObjectFlagsShowColumns
 }
 
-func determineQualifier(scanner *Scanner, lexer *mysql.MySQLLexer, offsetInLine int) (string, ObjectFlags) {
+func determineQualifier(scanner *Scanner, lexer *Lexer) (string, ObjectFlags) {
 	// Five possible positions here:
 	//   - In the first id (including the position directly after the last char).
 	//   - In the space between first id and a dot.
@@ -880,7 +1215,7 @@ func determineQualifier(scanner *Scanner, lexer *mysql.MySQLLexer, offsetInLine
 	qualifier := ""
 	temp := ""
 	if lexer.IsIdentifier(scanner.TokenType()) {
-		temp = unquote(scanner.TokenText())
+		temp = unquoteIdentifier(scanner.TokenText(), lexer.SQLMode)
 		scanner.Next(true /* skipHidden */)
 	}
 
@@ -0,0 +1,104 @@
+package completion
+
+import (
+	"strings"
+)
+
+// QuoteIdentifier returns name the way it must be written in a statement. Names which are reserved words of the
+// server version (see Completer.ServerVersion), which look like numbers or which contain characters not allowed in
+// unquoted identifiers are quoted with backticks, or with double quotes in ANSI_QUOTES mode. Other names are
+// returned as is.
+func QuoteIdentifier(name string, mode SQLMode, serverVersion int) string {
+	if !needsQuotes(name, serverVersion) {
+		return name
+	}
+	return quoteWith(name, mode.identifierQuote())
+}
+
+// quoteWith encloses name in the given quote, doubling the quotes within.
+func quoteWith(name string, quote rune) string {
+	q := string(quote)
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// needsQuotes returns true if name isn't lexed as an identifier when written without quotes.
+func needsQuotes(name string, serverVersion int) bool {
+	if len(name) == 0 {
+		return true
+	}
+	for _, r := range name {
+		if !isUnquotedIdentifierRune(r) {
+			return true
+		}
+	}
+	if isNumber(name) {
+		return true
+	}
+	return isReservedWord(serverVersion, strings.ToUpper(name))
+}
+
+// isUnquotedIdentifierRune returns true for the characters the lexer accepts in unquoted identifiers.
+func isUnquotedIdentifierRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '$' ||
+		(r >= 0x80 && r <= 0xFFFF)
+}
+
+// isNumber returns true for names consisting of identifier characters which are lexed as a number, like 123, 1e5,
+// 0x1F or 0b101.
+func isNumber(name string) bool {
+	digits := func(s string, isDigit func(r rune) bool) bool {
+		if len(s) == 0 {
+			return false
+		}
+		for _, r := range s {
+			if !isDigit(r) {
+				return false
+			}
+		}
+		return true
+	}
+	isDecimal := func(r rune) bool { return r >= '0' && r <= '9' }
+	isHex := func(r rune) bool { return isDecimal(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') }
+	isBinary := func(r rune) bool { return r == '0' || r == '1' }
+
+	switch {
+	case digits(name, isDecimal):
+		return true
+	case strings.HasPrefix(name, "0x"):
+		return digits(name[2:], isHex)
+	case strings.HasPrefix(name, "0b"):
+		return digits(name[2:], isBinary)
+	}
+	if index := strings.IndexAny(name, "eE"); index > 0 {
+		return digits(name[:index], isDecimal) && digits(name[index+1:], isDecimal)
+	}
+	return false
+}
+
+// identifierQuote returns the character identifiers are quoted with in this mode.
+func (m SQLMode) identifierQuote() rune {
+	if m&SQLModeANSIQuotes != 0 {
+		return '"'
+	}
+	return '`'
+}
+
+// quoteIdentifiers sets the insert text of all items to their quoted label, where quoting is needed, and marks
+// them as identifiers.
+func (m CompletionMap) quoteIdentifiers(mode SQLMode, serverVersion int) {
+	for key, item := range m {
+		item.InsertText = QuoteIdentifier(item.Label, mode, serverVersion)
+		item.identifier = true
+		m[key] = item
+	}
+}
+
+// completeQuote makes the identifier items finish the quote the user started typing: their insert text is quoted
+// with the same quote, which replaces the typed one.
+func completeQuote(items []CompletionItem, quote rune) {
+	for i := range items {
+		if items[i].identifier {
+			items[i].InsertText = quoteWith(items[i].Label, quote)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          SQLMode
+		serverVersion int
+		want          string
+	}{
+		{name: "orders", want: "orders"},
+		{name: "order", want: "`order`"},
+		{name: "Order", want: "`Order`"},
+		{name: "order", mode: SQLModeANSIQuotes, want: `"order"`},
+		{name: "my table", want: "`my table`"},
+		{name: "a`b", want: "`a``b`"},
+		{name: `a"b`, mode: SQLModeANSIQuotes, want: `"a""b"`},
+		{name: "日付", want: "日付"},
+		{name: "表😀", want: "`表😀`"},
+		{name: "$price_2", want: "$price_2"},
+		{name: "123", want: "`123`"},
+		{name: "1e5", want: "`1e5`"},
+		{name: "0x1F", want: "`0x1F`"},
+		{name: "1e", want: "1e"},
+		{name: "1st", want: "1st"},
+		{name: "", want: "``"},
+		// LATERAL is reserved since 8.0.
+		{name: "lateral", want: "`lateral`"},
+		{name: "lateral", serverVersion: ServerVersion57, want: "lateral"},
+		{name: "lateral", serverVersion: 80032, want: "`lateral`"},
+	}
+
+	a := require.New(t)
+	for _, test := range tests {
+		a.Equal(test.want, QuoteIdentifier(test.name, test.mode, test.serverVersion), test.name)
+	}
+}
+
+const quotingCatalog = `
+schemas:
+  - name: db
+    tables:
+      - name: order
+        columns:
+          - name: my column
+          - name: 日付
+      - name: plain
+`
+
+func TestQuotedInsertText(t *testing.T) {
+	a := require.New(t)
+	catalog, err := NewCatalogFromYAML([]byte(quotingCatalog))
+	a.NoError(err)
+
+	insertTexts := func(mode SQLMode, input string) map[string]string {
+		completer := NewCompleter()
+		completer.SQLMode = mode
+		text, caretOffset := catchCaret(input)
+		result := completer.CompleteScript(text, 1, caretOffset, "db", true, catalog)
+		insertTexts := make(map[string]string)
+		for _, item := range result.Items {
+			insertTexts[item.String()] = item.InsertText
+		}
+		return insertTexts
+	}
+
+	items := insertTexts(0, "SELECT | FROM `order`")
+	a.Equal("`my column`", items["7(my column)"])
+	a.Equal("日付", items["7(日付)"])
+	a.Equal("`order`", items["3(order)"])
+	a.Equal("DISTINCT", items["1(DISTINCT)"])
+
+	items = insertTexts(SQLModeANSIQuotes, "SELECT * FROM |")
+	a.Equal(`"order"`, items["3(order)"])
+	a.Equal("plain", items["3(plain)"])
+
+	// A typed opening quote is finished, even for names which don't need quotes.
+	items = insertTexts(0, "SELECT * FROM `pl|")
+	a.Equal("`plain`", items["3(plain)"])
+	a.Equal("`order`", items["3(order)"])
+	items = insertTexts(SQLModeANSIQuotes, `SELECT * FROM "pl|`)
+	a.Equal(`"plain"`, items["3(plain)"])
+}
@@ -0,0 +1,79 @@
+package completion
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TestConcurrentCompletion runs the test corpus from many goroutines at once, sharing one completer and catalog.
+// Run it with the race detector enabled.
+func TestConcurrentCompletion(t *testing.T) {
+	a := require.New(t)
+	yamlFile, err := os.Open("testdata/data.yaml")
+	a.NoError(err)
+	byteValue, err := io.ReadAll(yamlFile)
+	a.NoError(yamlFile.Close())
+	a.NoError(err)
+	var tests []candidatesTest
+	a.NoError(yaml.Unmarshal(byteValue, &tests))
+
+	// Half of the requests use a fresh completer, the others the default one which other tests use as well.
+	const rounds = 100
+	completer := NewCompleter()
+	results := make([][]string, len(tests)*rounds)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				results[i] = completionLabels(completer, tests[i%len(tests)].Input)
+			} else {
+				results[i] = completionLabels(defaultCompleter, tests[i%len(tests)].Input)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		a.Equal(tests[i%len(tests)].Want, result, tests[i%len(tests)].Input)
+	}
+}
+
+// TestConcurrentCatalogCompletion shares a catalog between concurrent requests going through the whole pipeline.
+func TestConcurrentCatalogCompletion(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+
+	complete := func() []CompletionItem {
+		parser, caretLine, caretOffset := parseCaret("SELECT c| FROM orders o JOIN customers c ON o.customer_id = c.id", 0)
+		result := GetCompletionResult(caretLine, caretOffset, "shop", true, parser, catalog)
+		result.Filter(MatchModeFuzzy)
+		result.Rank(UsageHints{"7(email)": 3})
+		return result.Items
+	}
+
+	want := complete()
+	a.NotEmpty(want)
+
+	results := make([][]CompletionItem, 200)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = complete()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		a.Equal(want, result)
+	}
+}
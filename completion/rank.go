@@ -0,0 +1,128 @@
+package completion
+
+import (
+	"fmt"
+	"sort"
+
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// UsageHints holds how often items have been picked before, keyed by their String() form. Frequently used
+// items are ranked higher.
+type UsageHints map[string]int
+
+var (
+	// Weights for item kinds, depending on the rule an item was collected for. In a column context columns
+	// come first, then table names and aliases, then other objects.
+	columnContextWeights = map[AutoCompletionImageType]int{
+		AutoCompletionImageTypeColumn:   100,
+		AutoCompletionImageTypeTable:    80,
+		AutoCompletionImageTypeView:     75,
+		AutoCompletionImageTypeSchema:   60,
+		AutoCompletionImageTypeFunction: 50,
+	}
+	tableContextWeights = map[AutoCompletionImageType]int{
+		AutoCompletionImageTypeTable:  100,
+		AutoCompletionImageTypeView:   90,
+		AutoCompletionImageTypeSchema: 70,
+	}
+	ruleWeights = map[int]map[AutoCompletionImageType]int{
+		mysql.MySQLParserRULE_columnRef:            columnContextWeights,
+		mysql.MySQLParserRULE_tableWild:            columnContextWeights,
+		mysql.MySQLParserRULE_columnInternalRef:    columnContextWeights,
+		mysql.MySQLParserRULE_tableRef:             tableContextWeights,
+		mysql.MySQLParserRULE_filterTableRef:       tableContextWeights,
+		mysql.MySQLParserRULE_tableRefWithWildcard: tableContextWeights,
+	}
+)
+
+const (
+	// The weight of objects collected for any other rule.
+	defaultObjectWeight = 60
+	// The weight of built-in functions, below all database objects.
+	builtinFunctionWeight = 40
+	// Bonus for columns and aliases of the query containing the caret, decreasing with each outer level.
+	levelBonus = 10
+	levelStep  = 5
+	// Bonus per previous use of an item, capped at maxUsage uses.
+	usageBonus = 3
+	maxUsage   = 10
+)
+
+// RankItems orders the items by relevance and assigns their SortText accordingly. The rank combines the item
+// kind (weighed against the rule it was collected for), the nesting level of the table reference it belongs to,
+// the match score assigned by FilterItems and the optional usage hints. Keywords always come last. Items with
+// equal rank keep their order.
+//
+// Completion results are ranked already, with the completer's Usage hints. Rank them again after filtering to take
+// the match quality into account.
+func RankItems(items []CompletionItem, hints UsageHints) []CompletionItem {
+	ranks := make([]int, len(items))
+	indexes := make([]int, len(items))
+	for i, item := range items {
+		ranks[i] = rank(item, hints)
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		left, right := items[indexes[i]], items[indexes[j]]
+		leftKeyword := left.Kind == AutoCompletionImageTypeKeyword
+		rightKeyword := right.Kind == AutoCompletionImageTypeKeyword
+		if leftKeyword != rightKeyword {
+			return rightKeyword
+		}
+		return ranks[indexes[i]] > ranks[indexes[j]]
+	})
+
+	result := make([]CompletionItem, len(items))
+	for i, index := range indexes {
+		result[i] = items[index]
+		result[i].SortText = fmt.Sprintf("%05d", i)
+	}
+	return result
+}
+
+// proximity returns the ranking bonus for items belonging to a table reference at the given nesting level.
+func proximity(level int) int {
+	if bonus := levelBonus - level*levelStep; bonus > 0 {
+		return bonus
+	}
+	return 0
+}
+
+// Rank orders the items of the result by relevance, see RankItems.
+func (r *CompletionResult) Rank(hints UsageHints) {
+	r.Items = RankItems(r.Items, hints)
+}
+
+func rank(item CompletionItem, hints UsageHints) int {
+	result := item.Score
+
+	switch item.Kind {
+	case AutoCompletionImageTypeKeyword:
+		// Keywords are ordered by match quality and usage only.
+	case AutoCompletionImageTypeFunction:
+		if item.builtin {
+			result += builtinFunctionWeight
+		} else if weight, ok := ruleWeights[item.rule][item.Kind]; ok {
+			result += weight
+		} else {
+			result += defaultObjectWeight
+		}
+	default:
+		if weight, ok := ruleWeights[item.rule][item.Kind]; ok {
+			result += weight
+		} else {
+			result += defaultObjectWeight
+		}
+	}
+
+	result += item.proximity
+
+	if uses := hints[item.String()]; uses > 0 {
+		if uses > maxUsage {
+			uses = maxUsage
+		}
+		result += uses * usageBonus
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankItems(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+
+	complete := func(input string) *CompletionResult {
+		parser, caretLine, caretOffset := parseCaret(input, 0)
+		return GetCompletionResult(caretLine, caretOffset, "shop", true, parser, catalog)
+	}
+	position := func(items []CompletionItem, value string) int {
+		for i, item := range items {
+			if item.String() == value {
+				return i
+			}
+		}
+		a.Failf("missing completion item", value)
+		return -1
+	}
+
+	result := complete("SELECT | FROM orders o")
+	result.Rank(nil)
+	items := result.Items
+	a.Equal(AutoCompletionImageTypeColumn, items[0].Kind)
+	a.Less(position(items, "7(total)"), position(items, "3(o)"), "columns before aliases")
+	a.Less(position(items, "3(o)"), position(items, "3(customers)"), "referenced tables before other tables")
+	a.Less(position(items, "3(customers)"), position(items, "2(shop)"), "tables before schemas")
+	a.Less(position(items, "2(shop)"), position(items, "5(abs())"), "schemas before built-in functions")
+	a.Less(position(items, "5(abs())"), position(items, "1(DISTINCT)"), "keywords last")
+	a.Equal(AutoCompletionImageTypeKeyword, items[len(items)-1].Kind)
+	for i, item := range items {
+		a.Equal(i, position(items, item.String()))
+		if i > 0 {
+			a.Less(items[i-1].SortText, item.SortText)
+		}
+	}
+
+	// Columns of the query containing the caret come before those of outer queries.
+	result = complete("SELECT * FROM customers c WHERE EXISTS (SELECT | FROM orders o)")
+	result.Rank(nil)
+	a.Less(position(result.Items, "7(total)"), position(result.Items, "7(email)"))
+
+	// Match quality and usage hints.
+	result = complete("SELECT c| FROM orders o")
+	result.Filter(MatchModeFuzzy)
+	result.Rank(nil)
+	a.Equal("7(created_at)", result.Items[0].String())
+	result.Rank(UsageHints{"7(customer_id)": 5})
+	a.Equal("7(customer_id)", result.Items[0].String())
+}
+
+func TestDefaultRanking(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+
+	complete := func(completer *Completer, input string) []CompletionItem {
+		text, caretOffset := catchCaret(input)
+		return completer.CompleteScript(text, 1, caretOffset, "shop", true, catalog).Items
+	}
+
+	// Items are ranked without calling Rank, keywords come after the objects.
+	items := complete(NewCompleter(), "SELECT | FROM orders o")
+	a.Equal(AutoCompletionImageTypeColumn, items[0].Kind)
+	a.Equal(AutoCompletionImageTypeKeyword, items[len(items)-1].Kind)
+	for i := 1; i < len(items); i++ {
+		a.Less(items[i-1].SortText, items[i].SortText)
+	}
+	items = complete(NewCompleter(), "SELECT * FROM |")
+	a.Equal(AutoCompletionImageTypeTable, items[0].Kind)
+	a.Equal(AutoCompletionImageTypeKeyword, items[len(items)-1].Kind)
+
+	// The completer's usage hints are taken into account.
+	completer := NewCompleter()
+	completer.Usage = UsageHints{"7(total)": 5}
+	items = complete(completer, "SELECT | FROM orders o")
+	a.Equal("7(total)", items[0].String())
+}
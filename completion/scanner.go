@@ -1,6 +1,10 @@
 package completion
 
-import "github.com/antlr4-go/antlr/v4"
+import (
+	"unicode/utf8"
+
+	"github.com/antlr4-go/antlr/v4"
+)
 
 type Scanner struct {
 	input      *antlr.CommonTokenStream
@@ -87,6 +91,11 @@ func (s *Scanner) SkipTokenSequence(list []int) bool {
 	return true
 }
 
+// Token returns the current token.
+func (s *Scanner) Token() antlr.Token {
+	return s.tokens[s.index]
+}
+
 func (s *Scanner) TokenText() string {
 	return s.tokens[s.index].GetText()
 }
@@ -116,6 +125,8 @@ func (s *Scanner) TokenSubText() string {
 	return cs.GetText(s.tokens[s.index].GetStart(), cs.Size()-1)
 }
 
+// AdvanceToPosition moves the scanner to the token at the given one-based line and zero-based column. Columns are
+// counted in code points, like the columns of the tokens. See AdvanceToCaret for other units.
 func (s *Scanner) AdvanceToPosition(line, offset int) bool {
 	if len(s.tokens) == 0 {
 		return false
@@ -150,3 +161,82 @@ func (s *Scanner) AdvanceToPosition(line, offset int) bool {
 
 	return true
 }
+
+// AdvanceToCaret works like AdvanceToPosition, but the column is counted in the given encoding.
+func (s *Scanner) AdvanceToCaret(line, column int, encoding CaretEncoding) bool {
+	if encoding != CaretEncodingRune {
+		input := s.input.GetTokenSource().GetInputStream()
+		column = encoding.runeColumn([]rune(input.GetText(0, input.Size()-1)), line, column)
+	}
+	return s.AdvanceToPosition(line, column)
+}
+
+// StatementBounds returns the indexes of the first and the last token of the statement which contains the token at
+// the given index. Statements are split like in SplitStatements, so DELIMITER commands in the input are honored and
+// the delimiter itself belongs to neither statement. A token of a DELIMITER command forms a statement of its own.
+// The first token is the first one on the default channel, or EOF for an empty statement.
+func (s *Scanner) StatementBounds(index int) (start, stop int) {
+	if len(s.tokens) == 0 {
+		return 0, 0
+	}
+	if index >= len(s.tokens) {
+		index = len(s.tokens) - 1
+	}
+
+	input := s.input.GetTokenSource().GetInputStream()
+	if input == nil {
+		return 0, len(s.tokens) - 1
+	}
+	var mode SQLMode
+	if lexer, ok := s.input.GetTokenSource().(*Lexer); ok {
+		mode = lexer.SQLMode
+	}
+
+	// Token positions are character indexes, while statements are located by byte offsets.
+	script := input.GetText(0, input.Size()-1)
+	position := s.tokens[index].GetStart()
+	if s.tokens[index].GetTokenType() == antlr.TokenEOF {
+		position = input.Size()
+	}
+	for _, statement := range splitScript(script, mode, CaretEncodingRune) {
+		first := utf8.RuneCountInString(script[:statement.Start.Offset])
+		last := first + utf8.RuneCountInString(statement.Text)
+		if position < first || position > last {
+			continue
+		}
+
+		start = index
+		for start > 0 && s.tokens[start-1].GetStart() >= first {
+			start--
+		}
+		for start < len(s.tokens)-1 && s.tokens[start].GetChannel() != antlr.TokenDefaultChannel {
+			start++
+		}
+		stop = index
+		for stop < len(s.tokens)-1 && s.tokens[stop+1].GetTokenType() != antlr.TokenEOF && s.tokens[stop+1].GetStart() < last {
+			stop++
+		}
+		if position >= last && s.tokens[index].GetTokenType() != antlr.TokenEOF && stop > start {
+			// The caret token is the delimiter which ends the statement.
+			stop = index - 1
+		}
+		if stop < start {
+			stop = start
+		}
+		return start, stop
+	}
+	return index, index
+}
+
+// TokenSubTextUntil returns the input text from the current token up to and including the token at stopIndex.
+func (s *Scanner) TokenSubTextUntil(stopIndex int) string {
+	if stopIndex >= len(s.tokens) {
+		return s.TokenSubText()
+	}
+	cs := s.tokens[s.index].GetTokenSource().GetInputStream()
+	stop := s.tokens[stopIndex].GetStop()
+	if stop < 0 { // EOF
+		stop = cs.Size() - 1
+	}
+	return cs.GetText(s.tokens[s.index].GetStart(), stop)
+}
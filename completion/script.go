@@ -0,0 +1,255 @@
+package completion
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+const defaultDelimiter = ";"
+
+// Statement is a single statement of a script, as split by SplitStatements.
+type Statement struct {
+	// Text is the statement without its delimiter.
+	Text string
+	// Start is the position of the statement text in the script.
+	Start Position
+	// Delimiter is the delimiter which was active for the statement.
+	Delimiter string
+}
+
+// SplitStatements splits a script into its statements the way the mysql command line client does. The client's
+// DELIMITER command (at the start of a line, between statements) changes the statement delimiter for all following
+// statements, which allows to write compound statements like CREATE PROCEDURE ... BEGIN ... END containing semicolons.
+// Delimiters within quotes and comments are ignored. DELIMITER commands and empty statements are not returned.
+// It uses the default completer.
+func SplitStatements(script string) []Statement {
+	return defaultCompleter.SplitStatements(script)
+}
+
+// SplitStatements splits a script into its statements like the package level SplitStatements, quotes are
+// recognized according to the completer's sql_mode and columns are counted in its CaretEncoding.
+func (c *Completer) SplitStatements(script string) []Statement {
+	var result []Statement
+	for _, statement := range splitScript(script, c.SQLMode, c.CaretEncoding) {
+		if len(strings.TrimSpace(statement.Text)) > 0 {
+			result = append(result, statement)
+		}
+	}
+	return result
+}
+
+// CompleteScript determines the completion candidates for a caret position in a script, using the default
+// completer.
+func CompleteScript(script string, caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, metadata MetadataProvider) *CompletionResult {
+	return defaultCompleter.CompleteScript(script, caretLine, caretOffset, defaultSchema, uppercaseKeywords, metadata)
+}
+
+// CompleteScript determines the completion candidates for a caret position in a script, which may contain
+// DELIMITER commands. Only the statement containing the caret is lexed and parsed, positions in the result refer
+// to the script. The result is empty if the caret is placed on a DELIMITER command.
+func (c *Completer) CompleteScript(script string, caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, metadata MetadataProvider) *CompletionResult {
+	return c.CompleteScriptContext(context.Background(), script, caretLine, caretOffset, defaultSchema, uppercaseKeywords, metadata)
+}
+
+// CompleteScriptContext works like CompleteScript, but stops collecting candidates when ctx is done or the
+// completer's budget is exhausted. The result is marked as incomplete then.
+func (c *Completer) CompleteScriptContext(ctx context.Context, script string, caretLine int, caretOffset int, defaultSchema string, uppercaseKeywords bool, metadata MetadataProvider) *CompletionResult {
+	statement, line, column, ok := c.statementAt(script, caretLine, caretOffset)
+	if !ok {
+		caret := positionOf([]rune(script), caretIndex([]rune(script), caretLine, caretOffset, c.CaretEncoding), c.CaretEncoding)
+		return &CompletionResult{Range: Range{Start: caret, End: caret}}
+	}
+
+	result := c.GetCompletionResultContext(ctx, line, column, defaultSchema, uppercaseKeywords, c.newParser(statement.Text), metadata)
+
+	// Map the replacement range back into the script.
+	result.Range.Start = statement.Start.add(result.Range.Start)
+	result.Range.End = statement.Start.add(result.Range.End)
+	return result
+}
+
+// statementAt returns the statement of the script which contains the caret, together with the caret line and
+// column relative to the statement. ok is false if the caret is placed on a DELIMITER command.
+func (c *Completer) statementAt(script string, caretLine int, caretOffset int) (statement Statement, line int, column int, ok bool) {
+	caret := positionOf([]rune(script), caretIndex([]rune(script), caretLine, caretOffset, c.CaretEncoding), c.CaretEncoding)
+
+	for _, statement := range splitScript(script, c.SQLMode, c.CaretEncoding) {
+		end := statement.Start.Offset + len(statement.Text)
+		if caret.Offset < statement.Start.Offset || caret.Offset > end {
+			continue
+		}
+
+		line = caretLine - statement.Start.Line + 1
+		column = caretOffset
+		if line == 1 {
+			column -= statement.Start.Column
+		}
+		return statement, line, column, true
+	}
+	return Statement{}, 0, 0, false
+}
+
+// newParser creates a parser for a single statement, lexed in the completer's sql_mode. Errors are not reported.
+func (c *Completer) newParser(statement string) *mysql.MySQLParser {
+	lexer := NewLexer(antlr.NewInputStream(statement), c.SQLMode)
+	lexer.RemoveErrorListeners()
+	parser := mysql.NewMySQLParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	parser.RemoveErrorListeners()
+	return parser
+}
+
+// add returns the script position of a position relative to the statement starting at p.
+func (p Position) add(relative Position) Position {
+	result := Position{
+		Line:   p.Line + relative.Line - 1,
+		Column: relative.Column,
+		Offset: p.Offset + relative.Offset,
+	}
+	if relative.Line == 1 {
+		result.Column += p.Column
+	}
+	return result
+}
+
+// splitScript returns all statements of a script, including empty ones. Columns are counted in the given encoding.
+func splitScript(script string, mode SQLMode, encoding CaretEncoding) []Statement {
+	var result []Statement
+	delimiter := defaultDelimiter
+
+	text := []rune(script)
+	position := Position{Line: 1}
+	start := position
+	startIndex := 0
+
+	// Walk over the text with an explicit index, keeping the position in sync. A statement is blank as long as it
+	// consists of whitespace and comments only.
+	i := 0
+	blank := true
+	step := func() {
+		r := text[i]
+		position.Offset += len(string(r))
+		if r == '\n' {
+			position.Line++
+			position.Column = 0
+		} else {
+			position.Column += encoding.width(r)
+		}
+		i++
+	}
+	finish := func(stop int) {
+		result = append(result, Statement{
+			Text:      string(text[startIndex:stop]),
+			Start:     start,
+			Delimiter: delimiter,
+		})
+	}
+
+	for i < len(text) {
+		if blank && isLineStart(text, i) {
+			if newDelimiter, length, ok := delimiterCommand(text[i:]); ok {
+				finish(i)
+				for end := i + length; i < end; {
+					step()
+				}
+				delimiter = newDelimiter
+				start, startIndex = position, i
+				continue
+			}
+		}
+
+		switch r := text[i]; {
+		case r == '\'' || r == '"' || r == '`':
+			blank = false
+			step()
+			for i < len(text) && text[i] != r {
+				if text[i] == '\\' && r != '`' && mode&SQLModeNoBackslashEscapes == 0 && i+1 < len(text) {
+					step()
+				}
+				step()
+			}
+			if i < len(text) {
+				step()
+			}
+		case r == '#' || (r == '-' && hasPrefix(text[i:], "--") && (i+2 == len(text) || unicode.IsSpace(text[i+2]))):
+			for i < len(text) && text[i] != '\n' {
+				step()
+			}
+		case r == '/' && hasPrefix(text[i:], "/*"):
+			step()
+			step()
+			for i < len(text) && !hasPrefix(text[i:], "*/") {
+				step()
+			}
+			if i < len(text) {
+				step()
+				step()
+			}
+		case hasPrefix(text[i:], delimiter):
+			finish(i)
+			for range []rune(delimiter) {
+				step()
+			}
+			start, startIndex = position, i
+			blank = true
+		default:
+			if !unicode.IsSpace(r) {
+				blank = false
+			}
+			step()
+		}
+	}
+	finish(len(text))
+
+	return result
+}
+
+// isLineStart returns true if there is nothing but spaces and tabs between the start of the line and the given index.
+func isLineStart(text []rune, index int) bool {
+	for index > 0 {
+		index--
+		switch text[index] {
+		case '\n':
+			return true
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// delimiterCommand matches a DELIMITER command at the start of text. It returns the new delimiter and the length
+// of the command, up to the end of the line.
+func delimiterCommand(text []rune) (delimiter string, length int, ok bool) {
+	const command = "delimiter"
+	if len(text) <= len(command) || !strings.EqualFold(string(text[:len(command)]), command) {
+		return "", 0, false
+	}
+	if text[len(command)] != ' ' && text[len(command)] != '\t' {
+		return "", 0, false
+	}
+
+	length = len(command)
+	for length < len(text) && text[length] != '\n' {
+		length++
+	}
+	fields := strings.Fields(string(text[len(command):length]))
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	return fields[0], length, true
+}
+
+func hasPrefix(text []rune, prefix string) bool {
+	for _, r := range prefix {
+		if len(text) == 0 || text[0] != r {
+			return false
+		}
+		text = text[1:]
+	}
+	return true
+}
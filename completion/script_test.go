@@ -0,0 +1,81 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const delimiterScript = `CREATE TABLE t1 (a INT); -- a comment; with semicolons
+DELIMITER //
+CREATE PROCEDURE p()
+BEGIN
+  SELECT 'a;b' FROM table1;
+  SELECT c FROM table2 t2;
+END //
+DELIMITER ;
+SELECT * FROM table3;`
+
+func TestSplitStatements(t *testing.T) {
+	a := require.New(t)
+	statements := SplitStatements(delimiterScript)
+	a.Len(statements, 4)
+
+	a.Equal("CREATE TABLE t1 (a INT)", statements[0].Text)
+	a.Equal(Position{Line: 1, Column: 0, Offset: 0}, statements[0].Start)
+	a.Equal(";", statements[0].Delimiter)
+
+	a.Equal(" -- a comment; with semicolons\n", statements[1].Text)
+
+	a.Equal("\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 'a;b' FROM table1;\n  SELECT c FROM table2 t2;\nEND ", statements[2].Text)
+	a.Equal(Position{Line: 2, Column: 12, Offset: 67}, statements[2].Start)
+	a.Equal("//", statements[2].Delimiter)
+
+	a.Equal("\nSELECT * FROM table3", statements[3].Text)
+	a.Equal(";", statements[3].Delimiter)
+}
+
+func TestCompleteScript(t *testing.T) {
+	a := require.New(t)
+
+	// Caret after "c" in the procedure body.
+	result := CompleteScript(delimiterScript, 6, 10, "db", true, testMetadata{})
+	var labels []string
+	for _, item := range result.Items {
+		labels = append(labels, item.String())
+	}
+	a.Contains(labels, "7(c1)")
+	a.Contains(labels, "3(t2)")
+	a.NotContains(labels, "7(c2)")
+	a.Equal("c", result.Prefix)
+	a.Equal(Position{Line: 6, Column: 9, Offset: 132}, result.Range.Start)
+	a.Equal(Position{Line: 6, Column: 10, Offset: 133}, result.Range.End)
+
+	// Caret after the last FROM.
+	result = CompleteScript(delimiterScript, 9, 14, "db", true, testMetadata{})
+	labels = nil
+	for _, item := range result.Items {
+		labels = append(labels, item.String())
+	}
+	a.Contains(labels, "3(table3)")
+
+	// No completion within a DELIMITER command.
+	result = CompleteScript(delimiterScript, 8, 5, "db", true, testMetadata{})
+	a.Empty(result.Items)
+}
+
+func TestDelimiterWithParser(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "DELIMITER //\nSELECT 1 //\nSELECT * FROM |", want: "3(table1)"},
+		{input: "DELIMITER //\nCREATE PROCEDURE p() outer_block: BEGIN SELECT 1; LEAVE |; END //\nDELIMITER ;", want: "20(outer_block)"},
+		{input: "DELIMITER //\nSELECT 1 //\nDELIMITER ;\nSELECT * FROM table2 t2 WHERE t2.|", want: "7(c1)"},
+	}
+
+	a := require.New(t)
+	for _, test := range tests {
+		a.Contains(completionLabels(defaultCompleter, test.input), test.want, test.input)
+	}
+}
@@ -0,0 +1,174 @@
+package completion
+
+import (
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// SignatureHelpResult holds the signatures of the function or procedure whose argument list contains the caret.
+type SignatureHelpResult struct {
+	// Signatures are the signatures found for the called name. A built-in function and a stored function may
+	// have the same name, so there can be more than one.
+	Signatures []Signature
+	// ActiveParameter is the zero-based index of the argument the caret is placed in.
+	ActiveParameter int
+}
+
+// Signature describes a function or procedure for signature help.
+type Signature struct {
+	// Label is the complete signature, e.g. "CONCAT(str, ...) RETURNS VARCHAR".
+	Label string
+	// Documentation is the description of a built-in function or the comment of a stored routine.
+	Documentation string
+	// Parameters are the labels of the parameters, as they appear in Label.
+	Parameters []string
+	// ActiveParameter is the index into Parameters for the argument the caret is placed in. Repeated parameters
+	// ("...") are mapped to the parameter they repeat. It is -1 if the signature has no parameter for the argument.
+	ActiveParameter int
+}
+
+// SignatureHelp returns the signatures of the function or procedure call the caret is placed in, using the
+// default completer.
+func SignatureHelp(script string, caretLine int, caretOffset int, defaultSchema string, metadata MetadataProvider) *SignatureHelpResult {
+	return defaultCompleter.SignatureHelp(script, caretLine, caretOffset, defaultSchema, metadata)
+}
+
+// SignatureHelp returns the signatures of the function or procedure call the caret is placed in, e.g. with the
+// caret in "CONCAT(a, |" or "CALL my_proc(1, |". Built-in functions are taken from the function catalog (see
+// BuiltinFunctions), stored functions and procedures from metadata, if it implements MetadataDescriber.
+// The result is nil if the caret isn't placed in an argument list or if the called name is unknown.
+func (c *Completer) SignatureHelp(script string, caretLine int, caretOffset int, defaultSchema string, metadata MetadataProvider) *SignatureHelpResult {
+	statement, line, column, ok := c.statementAt(script, caretLine, caretOffset)
+	if !ok {
+		return nil
+	}
+	parser := c.newParser(statement.Text)
+	text := parserText(parser)
+	caret := caretIndex(text, line, column, c.CaretEncoding)
+	if c.caretReason(parser, line, column) == NoCompletionReasonComment {
+		return nil
+	}
+
+	scanner := NewScanner(parser.GetTokenStream().(*antlr.CommonTokenStream))
+	scanner.AdvanceToCaret(line, column, c.CaretEncoding)
+	call, ok := findCall(scanner, caret, lexerOf(parser).SQLMode)
+	if !ok {
+		return nil
+	}
+
+	result := &SignatureHelpResult{ActiveParameter: call.argument}
+	schema := call.schema
+	if len(schema) == 0 {
+		schema = defaultSchema
+	}
+	describer, _ := metadata.(MetadataDescriber)
+
+	if call.procedure {
+		if describer != nil {
+			if routine := describer.DescribeRoutine(schema, call.name, RoutineTypeProcedure); routine != nil {
+				result.Signatures = append(result.Signatures, routineSignature(routine, call.argument))
+			}
+		}
+	} else {
+		if function := LookupBuiltinFunction(call.name); function != nil && len(call.schema) == 0 && function.availableIn(c.ServerVersion) {
+			result.Signatures = append(result.Signatures, Signature{
+				Label:           function.Signature(),
+				Documentation:   function.Description,
+				Parameters:      function.Parameters,
+				ActiveParameter: activeParameter(function.Parameters, call.argument),
+			})
+		}
+		if describer != nil {
+			if routine := describer.DescribeRoutine(schema, call.name, RoutineTypeFunction); routine != nil {
+				result.Signatures = append(result.Signatures, routineSignature(routine, call.argument))
+			}
+		}
+	}
+
+	if len(result.Signatures) == 0 {
+		return nil
+	}
+	return result
+}
+
+// routineCall is a function or procedure call found by findCall.
+type routineCall struct {
+	schema    string
+	name      string
+	procedure bool
+	// The index of the argument containing the caret.
+	argument int
+}
+
+// findCall walks back from the caret over balanced parentheses and counts the commas until it reaches the opening
+// parenthesis of the argument list containing the caret. The scanner must be positioned at the caret.
+func findCall(scanner *Scanner, caret int, mode SQLMode) (call routineCall, ok bool) {
+	// Start with the last token before the caret.
+	if scanner.Token().GetStart() >= caret && !scanner.Previous(false /* skipHidden */) {
+		return call, false
+	}
+
+	depth := 0
+	for {
+		found := false
+		switch scanner.TokenType() {
+		case mysql.MySQLLexerCLOSE_PAR_SYMBOL:
+			depth++
+		case mysql.MySQLLexerOPEN_PAR_SYMBOL:
+			if depth == 0 {
+				found = true
+			}
+			depth--
+		case mysql.MySQLLexerCOMMA_SYMBOL:
+			if depth == 0 {
+				call.argument++
+			}
+		case mysql.MySQLLexerSEMICOLON_SYMBOL:
+			return call, false
+		}
+		if found {
+			break
+		}
+		if !scanner.Previous(true /* skipHidden */) {
+			return call, false
+		}
+	}
+
+	// The name directly precedes the parenthesis, possibly qualified by a schema.
+	if !scanner.Previous(true /* skipHidden */) || !isWordToken(scanner.Token()) {
+		return call, false
+	}
+	call.name = unquoteIdentifier(scanner.TokenText(), mode)
+
+	if scanner.Previous(true /* skipHidden */) && scanner.Is(mysql.MySQLLexerDOT_SYMBOL) {
+		if !scanner.Previous(true /* skipHidden */) || !isWordToken(scanner.Token()) {
+			return call, false
+		}
+		call.schema = unquoteIdentifier(scanner.TokenText(), mode)
+		scanner.Previous(true /* skipHidden */)
+	}
+	call.procedure = scanner.Is(mysql.MySQLLexerCALL_SYMBOL)
+	return call, true
+}
+
+func routineSignature(routine *RoutineMetadata, argument int) Signature {
+	parameters := routine.parameterLabels()
+	return Signature{
+		Label:           routine.Signature(),
+		Documentation:   routine.Comment,
+		Parameters:      parameters,
+		ActiveParameter: activeParameter(parameters, argument),
+	}
+}
+
+// activeParameter maps an argument index to the index of the parameter it is passed for. A final "..." repeats
+// the parameter before it.
+func activeParameter(parameters []string, argument int) int {
+	if n := len(parameters); n >= 2 && parameters[n-1] == "..." && argument >= n-2 {
+		return n - 2
+	}
+	if argument < len(parameters) {
+		return argument
+	}
+	return -1
+}
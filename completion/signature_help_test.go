@@ -0,0 +1,57 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureHelp(t *testing.T) {
+	a := require.New(t)
+	catalog, err := LoadCatalog("testdata/catalog.yaml")
+	a.NoError(err)
+	help := func(input string) *SignatureHelpResult {
+		text, caretOffset := catchCaret(input)
+		return SignatureHelp(text, 1, caretOffset, "shop", catalog)
+	}
+
+	result := help("SELECT CONCAT(a, |")
+	a.NotNil(result)
+	a.Equal(1, result.ActiveParameter)
+	a.Len(result.Signatures, 1)
+	a.Equal("CONCAT(str, ...) RETURNS VARCHAR", result.Signatures[0].Label)
+	a.Equal(0, result.Signatures[0].ActiveParameter)
+
+	// Nested calls and parentheses are skipped.
+	result = help("SELECT SUBSTRING(name, LENGTH(name) - (1 + 2), |) FROM customers")
+	a.NotNil(result)
+	a.Equal(2, result.ActiveParameter)
+	a.Equal("SUBSTRING(str, pos, [len]) RETURNS VARCHAR", result.Signatures[0].Label)
+	a.Equal(2, result.Signatures[0].ActiveParameter)
+	result = help("SELECT CONCAT(a, LENGTH(|")
+	a.NotNil(result)
+	a.Equal(0, result.ActiveParameter)
+	a.Contains(result.Signatures[0].Label, "LENGTH(")
+
+	// Stored routines come from the metadata.
+	result = help("CALL archive_orders(NOW(), |")
+	a.NotNil(result)
+	a.Equal(1, result.ActiveParameter)
+	a.Equal([]string{"IN before datetime", "OUT archived int"}, result.Signatures[0].Parameters)
+	a.Equal(1, result.Signatures[0].ActiveParameter)
+	result = help("SELECT shop.order_count(|")
+	a.NotNil(result)
+	a.Equal(0, result.ActiveParameter)
+	a.Len(result.Signatures, 1)
+	a.Contains(result.Signatures[0].Label, "order_count(")
+
+	// Too many arguments.
+	result = help("CALL archive_orders(1, 2, |")
+	a.NotNil(result)
+	a.Equal(-1, result.Signatures[0].ActiveParameter)
+
+	a.Nil(help("SELECT | FROM customers"))
+	a.Nil(help("SELECT CONCAT(a, b) |"))
+	a.Nil(help("SELECT unknown_function(|"))
+	a.Nil(help("SELECT CONCAT(a, /* | */"))
+}
@@ -0,0 +1,168 @@
+package completion
+
+import (
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// SQLMode holds the sql_mode flags which change how statements are lexed.
+type SQLMode int
+
+const (
+	// SQLModeANSIQuotes makes double quoted text an identifier, like text in backticks.
+	SQLModeANSIQuotes SQLMode = 1 << iota
+	// SQLModePipesAsConcat makes || the string concatenation operator instead of a synonym for OR.
+	SQLModePipesAsConcat
+	// SQLModeHighNotPrecedence gives NOT the precedence of !.
+	SQLModeHighNotPrecedence
+	// SQLModeNoBackslashEscapes makes the backslash an ordinary character in strings.
+	SQLModeNoBackslashEscapes
+)
+
+var sqlModes = map[string]SQLMode{
+	"ANSI_QUOTES":          SQLModeANSIQuotes,
+	"PIPES_AS_CONCAT":      SQLModePipesAsConcat,
+	"HIGH_NOT_PRECEDENCE":  SQLModeHighNotPrecedence,
+	"NO_BACKSLASH_ESCAPES": SQLModeNoBackslashEscapes,
+	"ANSI":                 SQLModeANSIQuotes | SQLModePipesAsConcat,
+	"DB2":                  SQLModeANSIQuotes | SQLModePipesAsConcat,
+	"MAXDB":                SQLModeANSIQuotes | SQLModePipesAsConcat,
+	"MSSQL":                SQLModeANSIQuotes | SQLModePipesAsConcat,
+	"ORACLE":               SQLModeANSIQuotes | SQLModePipesAsConcat,
+	"POSTGRESQL":           SQLModeANSIQuotes | SQLModePipesAsConcat,
+}
+
+// ParseSQLMode converts a value of the sql_mode system variable, e.g. "ANSI_QUOTES,STRICT_TRANS_TABLES", into
+// the flags which matter for lexing. Combination modes like ANSI are expanded, all other modes are ignored.
+func ParseSQLMode(sqlMode string) SQLMode {
+	var result SQLMode
+	for _, mode := range strings.Split(sqlMode, ",") {
+		result |= sqlModes[strings.ToUpper(strings.TrimSpace(mode))]
+	}
+	return result
+}
+
+// Lexer is a MySQLLexer which follows an sql_mode. The grammar doesn't know about sql_mode, so the lexer
+// changes the token types the way the server would lex them.
+type Lexer struct {
+	*mysql.MySQLLexer
+	SQLMode SQLMode
+
+	// The index of the character following the last token returned.
+	next int
+}
+
+// NewLexer creates a lexer for the given sql_mode. Use it instead of mysql.NewMySQLLexer for the parser passed
+// to the completer, the completer picks up the mode from it.
+func NewLexer(input antlr.CharStream, mode SQLMode) *Lexer {
+	return &Lexer{
+		MySQLLexer: mysql.NewMySQLLexer(input),
+		SQLMode:    mode,
+	}
+}
+
+// NextToken returns the next token from the MySQLLexer, with its type adjusted to the sql_mode.
+func (l *Lexer) NextToken() antlr.Token {
+	token := l.nextToken()
+	if token.GetTokenType() != antlr.TokenEOF {
+		l.next = token.GetStop() + 1
+	}
+	return token
+}
+
+func (l *Lexer) nextToken() antlr.Token {
+	if l.SQLMode&SQLModeNoBackslashEscapes != 0 {
+		if token := l.stringToken(); token != nil {
+			return token
+		}
+	}
+
+	token := l.MySQLLexer.NextToken()
+	tokenType := l.SQLMode.tokenType(token.GetTokenType())
+	if tokenType == token.GetTokenType() {
+		return token
+	}
+	return l.GetTokenFactory().Create(token.GetSource(), tokenType, "", token.GetChannel(), token.GetStart(),
+		token.GetStop(), token.GetLine(), token.GetColumn())
+}
+
+// stringToken scans a quoted string starting at the current position without backslash escapes, where the
+// MySQLLexer would take a backslash before a quote as an escape. The token keeps the original text. It returns
+// nil if there is no string at the current position or it isn't terminated, the MySQLLexer handles these.
+func (l *Lexer) stringToken() antlr.Token {
+	input := l.GetInputStream()
+	start := input.Index()
+	if start != l.next {
+		return nil // The MySQLLexer has tokens pending, which it scanned ahead.
+	}
+
+	tokenType, quote := mysql.MySQLLexerSINGLE_QUOTED_TEXT, input.LA(1)
+	length := 1
+	if quote == 'n' || quote == 'N' {
+		tokenType, quote = mysql.MySQLLexerNCHAR_TEXT, input.LA(2)
+		length = 2
+		if quote != '\'' {
+			return nil
+		}
+	}
+	switch quote {
+	case '\'':
+	case '"':
+		tokenType = l.SQLMode.tokenType(mysql.MySQLLexerDOUBLE_QUOTED_TEXT)
+	default:
+		return nil
+	}
+
+	// A quote within the string is doubled.
+	for {
+		length++
+		r := input.LA(length)
+		if r == antlr.TokenEOF {
+			return nil
+		}
+		if r == quote {
+			if input.LA(length+1) != quote {
+				break
+			}
+			length++
+		}
+	}
+
+	line, column := l.GetLine(), l.GetCharPositionInLine()
+	for i := 0; i < length; i++ {
+		l.Interpreter.Consume(input)
+	}
+	return l.GetTokenFactory().Create(l.GetTokenSourceCharStreamPair(), tokenType, "", antlr.TokenDefaultChannel,
+		start, start+length-1, line, column)
+}
+
+func (m SQLMode) tokenType(tokenType int) int {
+	switch {
+	case tokenType == mysql.MySQLLexerDOUBLE_QUOTED_TEXT && m&SQLModeANSIQuotes != 0:
+		return mysql.MySQLLexerBACK_TICK_QUOTED_ID
+	case tokenType == mysql.MySQLLexerLOGICAL_OR_OPERATOR && m&SQLModePipesAsConcat != 0:
+		return mysql.MySQLLexerCONCAT_PIPES_SYMBOL
+	case tokenType == mysql.MySQLLexerNOT_SYMBOL && m&SQLModeHighNotPrecedence != 0:
+		return mysql.MySQLLexerNOT2_SYMBOL
+	}
+	return tokenType
+}
+
+// isIdentifierQuote returns true if r quotes identifiers in this mode.
+func (m SQLMode) isIdentifierQuote(r rune) bool {
+	return r == '`' || (r == '"' && m&SQLModeANSIQuotes != 0)
+}
+
+// lexerOf returns the lexer of the parser. Parsers built on a plain MySQLLexer, or on any other token source, use
+// the default sql_mode.
+func lexerOf(parser *mysql.MySQLParser) *Lexer {
+	switch source := parser.GetTokenStream().GetTokenSource().(type) {
+	case *Lexer:
+		return source
+	case *mysql.MySQLLexer:
+		return &Lexer{MySQLLexer: source}
+	}
+	return &Lexer{MySQLLexer: mysql.NewMySQLLexer(nil)}
+}
@@ -0,0 +1,121 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/antlr4-go/antlr/v4"
+	mysql "github.com/bytebase/mysql-parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSQLMode(t *testing.T) {
+	a := require.New(t)
+	a.Equal(SQLMode(0), ParseSQLMode(""))
+	a.Equal(SQLModeANSIQuotes, ParseSQLMode("STRICT_TRANS_TABLES, ansi_quotes"))
+	a.Equal(SQLModeANSIQuotes|SQLModePipesAsConcat, ParseSQLMode("ANSI"))
+	a.Equal(SQLModeHighNotPrecedence|SQLModeNoBackslashEscapes, ParseSQLMode("HIGH_NOT_PRECEDENCE,NO_BACKSLASH_ESCAPES"))
+}
+
+func TestSQLModeLexer(t *testing.T) {
+	a := require.New(t)
+	tokenTypes := func(mode SQLMode, text string) []int {
+		stream := antlr.NewCommonTokenStream(NewLexer(antlr.NewInputStream(text), mode), antlr.TokenDefaultChannel)
+		stream.Fill()
+		var result []int
+		for _, token := range stream.GetAllTokens() {
+			if token.GetChannel() == antlr.TokenDefaultChannel && token.GetTokenType() != antlr.TokenEOF {
+				result = append(result, token.GetTokenType())
+			}
+		}
+		return result
+	}
+
+	a.Equal([]int{mysql.MySQLLexerDOUBLE_QUOTED_TEXT}, tokenTypes(0, `"a"`))
+	a.Equal([]int{mysql.MySQLLexerBACK_TICK_QUOTED_ID}, tokenTypes(SQLModeANSIQuotes, `"a"`))
+	a.Equal([]int{mysql.MySQLLexerIDENTIFIER, mysql.MySQLLexerLOGICAL_OR_OPERATOR, mysql.MySQLLexerIDENTIFIER}, tokenTypes(0, "a || b"))
+	a.Equal([]int{mysql.MySQLLexerIDENTIFIER, mysql.MySQLLexerCONCAT_PIPES_SYMBOL, mysql.MySQLLexerIDENTIFIER}, tokenTypes(SQLModePipesAsConcat, "a || b"))
+	a.Equal([]int{mysql.MySQLLexerNOT2_SYMBOL, mysql.MySQLLexerIDENTIFIER}, tokenTypes(SQLModeHighNotPrecedence, "NOT a"))
+
+	// Without escapes the string ends at the second quote, with them it runs to the end.
+	a.Equal([]int{mysql.MySQLLexerSINGLE_QUOTED_TEXT, mysql.MySQLLexerIDENTIFIER}, tokenTypes(SQLModeNoBackslashEscapes, `'a\' b`))
+	a.Equal([]int{mysql.MySQLLexerNCHAR_TEXT, mysql.MySQLLexerCOMMA_SYMBOL, mysql.MySQLLexerDOUBLE_QUOTED_TEXT},
+		tokenTypes(SQLModeNoBackslashEscapes, `N'a\', "b\"`))
+	a.Equal([]int{mysql.MySQLLexerBACK_TICK_QUOTED_ID}, tokenTypes(SQLModeNoBackslashEscapes|SQLModeANSIQuotes, `"b\"`))
+
+	// The tokens keep the original text, backslashes included.
+	stream := antlr.NewCommonTokenStream(NewLexer(antlr.NewInputStream("SELECT 'it''s\\',\n 'a\\' b"), SQLModeNoBackslashEscapes), antlr.TokenDefaultChannel)
+	stream.Fill()
+	var texts []string
+	for _, token := range stream.GetAllTokens() {
+		if token.GetChannel() == antlr.TokenDefaultChannel && token.GetTokenType() != antlr.TokenEOF {
+			texts = append(texts, token.GetText())
+		}
+	}
+	a.Equal([]string{"SELECT", `'it''s\'`, ",", `'a\'`, "b"}, texts)
+	b := stream.Get(stream.Size() - 2)
+	a.Equal("b", b.GetText())
+	a.Equal(2, b.GetLine())
+	a.Equal(6, b.GetColumn())
+}
+
+func TestSQLModeCompletion(t *testing.T) {
+	a := require.New(t)
+	complete := func(mode SQLMode, input string) *CompletionResult {
+		parser, caretLine, caretOffset := parseCaret(input, mode)
+		return GetCompletionResult(caretLine, caretOffset, "db", true, parser, testMetadata{})
+	}
+	labels := func(result *CompletionResult) []string {
+		var labels []string
+		for _, item := range result.Items {
+			labels = append(labels, item.String())
+		}
+		return labels
+	}
+
+	// Double quoted table names are only references with ANSI_QUOTES.
+	result := complete(SQLModeANSIQuotes, `SELECT | FROM "table2"`)
+	a.Contains(labels(result), "7(c1)")
+	result = complete(0, `SELECT | FROM "table2"`)
+	a.NotContains(labels(result), "7(c1)")
+
+	// An open double quote starts a quoted identifier with ANSI_QUOTES only.
+	result = complete(SQLModeANSIQuotes, `SELECT * FROM "tab|`)
+	a.Equal(`"tab`, result.Prefix)
+	result = complete(0, `SELECT * FROM "tab|`)
+	a.Empty(result.Prefix)
+}
+
+// tokenSource is a token source the completer doesn't know.
+type tokenSource struct {
+	*mysql.MySQLLexer
+}
+
+func TestForeignTokenSource(t *testing.T) {
+	a := require.New(t)
+	text, caretOffset := catchCaret("SELECT | FROM table2")
+	lexer := tokenSource{mysql.NewMySQLLexer(antlr.NewInputStream(text))}
+	parser := mysql.NewMySQLParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	parser.RemoveErrorListeners()
+	var labels []string
+	for _, item := range GetCompletionItems(1, caretOffset, "db", true, parser, testMetadata{}) {
+		labels = append(labels, item.String())
+	}
+	a.Contains(labels, "7(c1)")
+}
+
+func TestCompleterSQLMode(t *testing.T) {
+	a := require.New(t)
+	completer := NewCompleter()
+	a.Len(completer.SplitStatements(`SELECT "a;\"b"; SELECT 1`), 2)
+
+	completer.SQLMode = SQLModeNoBackslashEscapes
+	a.Len(completer.SplitStatements(`SELECT "a\"; SELECT 1`), 2)
+
+	completer.SQLMode = SQLModeANSIQuotes
+	result := completer.CompleteScript("SELECT 1;\nSELECT | FROM \"table2\"", 2, 7, "db", true, testMetadata{})
+	var labels []string
+	for _, item := range result.Items {
+		labels = append(labels, item.String())
+	}
+	a.Contains(labels, "7(c1)")
+}
@@ -0,0 +1,54 @@
+-- MySQL dump 10.13  Distrib 8.0.33, for Linux (x86_64)
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!40101 SET NAMES utf8mb4 */;
+
+CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 */;
+USE `shop`;
+
+DROP TABLE IF EXISTS `customers`;
+CREATE TABLE `customers` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL COMMENT 'Full name',
+  `email` varchar(255) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Registered customers';
+
+CREATE TABLE `orders` (
+  `id` bigint unsigned NOT NULL PRIMARY KEY,
+  `customer_id` bigint unsigned NOT NULL,
+  `total` decimal(10,2) NOT NULL DEFAULT '0.00',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  KEY (`customer_id`)
+) ENGINE=InnoDB;
+
+CREATE INDEX idx_created ON orders (created_at);
+ALTER TABLE orders ADD COLUMN note text, MODIFY total decimal(12,2) NOT NULL, RENAME COLUMN note TO remark;
+CREATE TABLE order_archive LIKE orders;
+
+CREATE VIEW order_totals AS SELECT o.customer_id, SUM(o.total) AS total, count(*) FROM orders o GROUP BY o.customer_id;
+CREATE VIEW recent_orders (order_id, placed) AS SELECT id, created_at FROM orders;
+
+CREATE FUNCTION order_count(customer bigint unsigned) RETURNS int DETERMINISTIC RETURN (SELECT COUNT(*) FROM orders WHERE customer_id = customer);
+CREATE PROCEDURE archive_orders(IN cutoff datetime, OUT archived int) SELECT 1;
+CREATE TRIGGER orders_before_insert BEFORE INSERT ON orders FOR EACH ROW SET NEW.total = 0;
+
+DELIMITER ;;
+/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`localhost`*/ /*!50003 TRIGGER `orders_after_update` AFTER UPDATE ON `orders` FOR EACH ROW BEGIN
+  IF NEW.total <> OLD.total THEN
+    INSERT INTO audit.log (message) VALUES (CONCAT('total changed: ', OLD.id));
+  END IF;
+END */;;
+DELIMITER ;
+
+DELIMITER //
+CREATE PROCEDURE recalculate_totals(IN since datetime)
+BEGIN
+  DECLARE changed int DEFAULT 0;
+  UPDATE orders SET total = total WHERE created_at >= since;
+END //
+DELIMITER ;
+
+CREATE EVENT nightly_cleanup ON SCHEDULE EVERY 1 DAY COMMENT 'Remove old rows' DO DELETE FROM order_archive;
+
+CREATE TABLE audit.log (id int, message text);
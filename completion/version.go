@@ -0,0 +1,83 @@
+package completion
+
+import (
+	mysql "github.com/bytebase/mysql-parser"
+)
+
+// Server versions in the form used by Completer.ServerVersion.
+const (
+	ServerVersion56 = 50600
+	ServerVersion57 = 50700
+	ServerVersion80 = 80000
+)
+
+var (
+	keywords56 = keywordSet(mysql.Keywords56)
+	keywords57 = keywordSet(mysql.Keywords57)
+	keywords80 = keywordSet(mysql.Keywords80)
+	// Keywords of any version, the grammar also has tokens which are no keywords (e.g. function names).
+	allKeywords = keywordSet(mysql.Keywords56, mysql.Keywords57, mysql.Keywords80)
+
+	reserved56 = reservedSet(mysql.Keywords56)
+	reserved57 = reservedSet(mysql.Keywords57)
+	reserved80 = reservedSet(mysql.Keywords80)
+	// Words reserved in any version, like the lexer checks them.
+	allReserved = reservedSet(mysql.Keywords56, mysql.Keywords57, mysql.Keywords80)
+)
+
+func keywordSet(lists ...[]mysql.Keyword) map[string]bool {
+	result := make(map[string]bool)
+	for _, list := range lists {
+		for _, keyword := range list {
+			result[keyword.Keyword] = true
+		}
+	}
+	return result
+}
+
+func reservedSet(lists ...[]mysql.Keyword) map[string]bool {
+	result := make(map[string]bool)
+	for _, list := range lists {
+		for _, keyword := range list {
+			if keyword.Reserved {
+				result[keyword.Keyword] = true
+			}
+		}
+	}
+	return result
+}
+
+// versionKeywords returns the keywords of a server version, or nil if all keywords are accepted.
+func versionKeywords(serverVersion int) map[string]bool {
+	switch {
+	case serverVersion <= 0:
+		return nil
+	case serverVersion < ServerVersion57:
+		return keywords56
+	case serverVersion < ServerVersion80:
+		return keywords57
+	default:
+		return keywords80
+	}
+}
+
+// hasKeyword returns false if the (upper case) word is a keyword of some server version, but not of the given one.
+func hasKeyword(serverVersion int, word string) bool {
+	keywords := versionKeywords(serverVersion)
+	return keywords == nil || keywords[word] || !allKeywords[word]
+}
+
+// isReservedWord returns true if the (upper case) word is reserved in the given server version. Without a version
+// words reserved in any version count.
+func isReservedWord(serverVersion int, word string) bool {
+	switch {
+	case serverVersion <= 0:
+		return allReserved[word]
+	case serverVersion < ServerVersion57:
+		return reserved56[word]
+	case serverVersion < ServerVersion80:
+		return reserved57[word]
+	default:
+		return reserved80[word]
+	}
+}
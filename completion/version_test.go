@@ -0,0 +1,57 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerVersion(t *testing.T) {
+	a := require.New(t)
+	complete := func(serverVersion int, input string) []string {
+		completer := NewCompleter()
+		completer.ServerVersion = serverVersion
+		return completionLabels(completer, input)
+	}
+
+	tests := []struct {
+		input string
+		// Candidates of 8.0 only, 5.7 only and both.
+		only80 []string
+		only57 []string
+		both   []string
+	}{
+		{
+			input:  "SELECT * FROM |",
+			only80: []string{"1(LATERAL)", "1(JSON_TABLE)"},
+			both:   []string{"1(DUAL)", "3(table1)"},
+		},
+		{
+			input:  "SELECT |",
+			only80: []string{"1(ROW_NUMBER)", "1(CUME_DIST)", "1(LAG)"},
+			only57: []string{"1(SQL_CACHE)", "1(MAX_STATEMENT_TIME)"},
+			both:   []string{"1(DISTINCT)", "1(COUNT)", "3(table1)"},
+		},
+	}
+
+	for _, test := range tests {
+		all := complete(0, test.input)
+		mysql57 := complete(ServerVersion57, test.input)
+		mysql80 := complete(80032, test.input)
+		a.Subset(all, mysql57, test.input)
+		a.Subset(all, mysql80, test.input)
+
+		for _, item := range test.only80 {
+			a.Contains(mysql80, item, test.input)
+			a.NotContains(mysql57, item, test.input)
+		}
+		for _, item := range test.only57 {
+			a.Contains(mysql57, item, test.input)
+			a.NotContains(mysql80, item, test.input)
+		}
+		for _, item := range test.both {
+			a.Contains(mysql57, item, test.input)
+			a.Contains(mysql80, item, test.input)
+		}
+	}
+}